@@ -0,0 +1,53 @@
+// Package admin contains authenticated HTTP handlers used to operate the service at runtime.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Authenticate passes through only requests carrying one of the known bearer tokens.
+// Tokens map a token to the name of the user it belongs to.
+func Authenticate(tokens map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+		user, ok := tokens[token]
+		if token == "" || !ok {
+			writeError(w, req, http.StatusUnauthorized, "unauthorized")
+
+			return
+		}
+
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextKey{}, user)))
+	})
+}
+
+// User returns the name of the authenticated admin user
+func User(req *http.Request) string {
+	user, _ := req.Context().Value(contextKey{}).(string)
+
+	return user
+}
+
+func writeJSON(w http.ResponseWriter, req *http.Request, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("admin: %s %s %d %s", req.Method, req.URL.String(), status, err.Error())
+
+		return
+	}
+
+	log.Printf("admin: %s %s %d %s", req.Method, req.URL.String(), status, User(req))
+}
+
+func writeError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	writeJSON(w, req, status, map[string]string{"message": message})
+}
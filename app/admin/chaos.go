@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+// ChaosHandler shows and changes fault injection settings of chaos clients.
+//
+//	GET /?provider=1 returns the config of one provider, or of all providers if the parameter is omitted
+//	PUT /?provider=1 replaces the config of a provider with the JSON body
+type ChaosHandler struct {
+	Clients map[provider.Provider]*provider.ChaosClient
+}
+
+func (h ChaosHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	providerType := provider.Provider(req.URL.Query().Get("provider"))
+
+	switch req.Method {
+	case http.MethodGet:
+		if providerType == "" {
+			configs := make(map[provider.Provider]provider.ChaosConfig, len(h.Clients))
+			for i := range h.Clients {
+				configs[i] = h.Clients[i].Config()
+			}
+
+			writeJSON(w, req, http.StatusOK, configs)
+
+			return
+		}
+
+		client, ok := h.Clients[providerType]
+		if !ok {
+			writeError(w, req, http.StatusNotFound, "unknown provider")
+
+			return
+		}
+
+		writeJSON(w, req, http.StatusOK, client.Config())
+	case http.MethodPut:
+		client, ok := h.Clients[providerType]
+		if !ok {
+			writeError(w, req, http.StatusNotFound, "unknown provider")
+
+			return
+		}
+
+		config := provider.ChaosConfig{}
+		if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+			writeError(w, req, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		if err := client.SetConfig(config); err != nil {
+			writeError(w, req, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		writeJSON(w, req, http.StatusOK, client.Config())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestChaosHandler(t *testing.T) {
+	client := provider.NewChaosClient(&provider.ContentProviderMock{Source: provider.Provider1})
+	handler := ChaosHandler{Clients: map[provider.Provider]*provider.ChaosClient{provider.Provider1: client}}
+
+	run := func(method, url, body string) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, httptest.NewRequest(method, url, strings.NewReader(body)))
+
+		return response
+	}
+
+	testCases := []struct {
+		method       string
+		url          string
+		body         string
+		expectedCode int
+	}{
+		{method: http.MethodGet, url: "/", expectedCode: http.StatusOK},
+		{method: http.MethodGet, url: "/?provider=1", expectedCode: http.StatusOK},
+		{method: http.MethodGet, url: "/?provider=9", expectedCode: http.StatusNotFound},
+		{method: http.MethodPut, url: "/?provider=9", body: `{}`, expectedCode: http.StatusNotFound},
+		{method: http.MethodPut, url: "/?provider=1", body: `{"error_rate":`, expectedCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/?provider=1", body: `{"error_rate":2}`, expectedCode: http.StatusBadRequest},
+		{method: http.MethodPut, url: "/?provider=1", body: `{"latency":"fixed","latency_mean":"soon"}`, expectedCode: http.StatusBadRequest},
+		{method: http.MethodDelete, url: "/?provider=1", expectedCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range testCases {
+		if response := run(test.method, test.url, test.body); response.Code != test.expectedCode {
+			t.Errorf("%s %s %s: response code is %d, want %d", test.method, test.url, test.body, response.Code, test.expectedCode)
+		}
+	}
+
+	if config := client.Config(); config != (provider.ChaosConfig{}) {
+		t.Fatalf("Got config %+v after rejected updates, want it unchanged", config)
+	}
+
+	response := run(http.MethodPut, "/?provider=1", `{"latency":"fixed","latency_mean":"150ms","error_rate":0.5}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Response code is %d, want 200", response.Code)
+	}
+
+	expected := provider.ChaosConfig{Latency: provider.LatencyFixed, LatencyMean: provider.Duration(time.Millisecond * 150), ErrorRate: 0.5}
+	if config := client.Config(); config != expected {
+		t.Fatalf("Got config %+v, want %+v", config, expected)
+	}
+
+	configs := map[provider.Provider]provider.ChaosConfig{}
+	if err := json.NewDecoder(run(http.MethodGet, "/", "").Body).Decode(&configs); err != nil {
+		t.Fatalf("couldn't decode response json: %v", err)
+	}
+	if configs[provider.Provider1] != expected {
+		t.Fatalf("Got configs %+v, want %+v for provider 1", configs, expected)
+	}
+}
@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+)
+
+const (
+	defaultPreviewCount = 10
+
+	// previews are limited the same way as feed requests
+	maxPreviewCount  = 100
+	maxPreviewOffset = 10 * 1000
+)
+
+// MixHandler shows and changes the content mix at runtime. It's meant to be mounted under Prefix.
+//
+//	GET  {prefix}                          returns the active version
+//	PUT  {prefix}                          validates and applies the mix from the JSON body
+//	POST {prefix}/validate                 validates the mix from the JSON body without applying it
+//	POST {prefix}/preview?count=&offset=   returns the provider sequence for the mix from the JSON body,
+//	                                       or for the active mix if the body is empty
+//	GET  {prefix}/history                  returns all applied versions
+//	POST {prefix}/rollback?version=        re-applies a previous version
+type MixHandler struct {
+	Prefix string
+	Store  *config.Store
+}
+
+type validationResult struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func (h MixHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch route := strings.TrimPrefix(req.URL.Path, h.Prefix); {
+	case route == "" && req.Method == http.MethodGet:
+		writeJSON(w, req, http.StatusOK, h.Store.CurrentVersion())
+	case route == "" && req.Method == http.MethodPut:
+		h.apply(w, req)
+	case route == "/validate" && req.Method == http.MethodPost:
+		h.validate(w, req)
+	case route == "/preview" && req.Method == http.MethodPost:
+		h.preview(w, req)
+	case route == "/history" && req.Method == http.MethodGet:
+		writeJSON(w, req, http.StatusOK, h.Store.History())
+	case route == "/rollback" && req.Method == http.MethodPost:
+		h.rollback(w, req)
+	default:
+		writeError(w, req, http.StatusNotFound, "not found")
+	}
+}
+
+func (h MixHandler) apply(w http.ResponseWriter, req *http.Request) {
+	mix := config.ContentMix{}
+	if err := json.NewDecoder(req.Body).Decode(&mix); err != nil {
+		writeError(w, req, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	version, err := h.Store.Apply(mix, User(req))
+	if err != nil {
+		writeError(w, req, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	log.Printf("admin: content mix version %d applied by %s", version.ID, version.AppliedBy)
+	writeJSON(w, req, http.StatusOK, version)
+}
+
+func (h MixHandler) validate(w http.ResponseWriter, req *http.Request) {
+	mix := config.ContentMix{}
+	if err := json.NewDecoder(req.Body).Decode(&mix); err != nil {
+		writeError(w, req, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err := mix.Validate(h.Store.Providers); err != nil {
+		writeJSON(w, req, http.StatusOK, validationResult{Error: err.Error()})
+
+		return
+	}
+
+	writeJSON(w, req, http.StatusOK, validationResult{Valid: true})
+}
+
+func (h MixHandler) preview(w http.ResponseWriter, req *http.Request) {
+	count, err := queryParamInt(req, "count", defaultPreviewCount)
+	if err != nil || count > maxPreviewCount {
+		writeError(w, req, http.StatusBadRequest, "invalid count")
+
+		return
+	}
+	offset, err := queryParamInt(req, "offset", 0)
+	if err != nil || offset > maxPreviewOffset {
+		writeError(w, req, http.StatusBadRequest, "invalid offset")
+
+		return
+	}
+
+	mix := config.ContentMix{}
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&mix); err != nil {
+			writeError(w, req, http.StatusBadRequest, err.Error())
+
+			return
+		}
+	}
+	if len(mix) == 0 {
+		mix = h.Store.Current()
+	}
+
+	if err := mix.Validate(h.Store.Providers); err != nil {
+		writeError(w, req, http.StatusUnprocessableEntity, err.Error())
+
+		return
+	}
+
+	writeJSON(w, req, http.StatusOK, mix.Sequence(count, offset))
+}
+
+func (h MixHandler) rollback(w http.ResponseWriter, req *http.Request) {
+	id, err := queryParamInt(req, "version", 0)
+	if err != nil {
+		writeError(w, req, http.StatusBadRequest, "invalid version")
+
+		return
+	}
+
+	version, err := h.Store.Rollback(id, User(req))
+	if err != nil {
+		writeError(w, req, http.StatusNotFound, err.Error())
+
+		return
+	}
+
+	log.Printf("admin: content mix rolled back to version %d as version %d by %s", version.RollbackOf, version.ID, version.AppliedBy)
+	writeJSON(w, req, http.StatusOK, version)
+}
+
+func queryParamInt(req *http.Request, key string, defaultValue int) (int, error) {
+	value := strings.TrimSpace(req.URL.Query().Get(key))
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	res, err := strconv.Atoi(value)
+	if err != nil || res < 0 {
+		return 0, strconv.ErrSyntax
+	}
+
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestMixHandler(t *testing.T) {
+	store := config.NewStore(config.DefaultContentMix, []provider.Provider{provider.Provider1, provider.Provider2})
+	handler := Authenticate(map[string]string{"token": "alice"}, MixHandler{Prefix: "/admin/mix", Store: store})
+
+	run := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		return response
+	}
+
+	if response := run(http.MethodGet, "/admin/mix", "", "wrong"); response.Code != http.StatusUnauthorized {
+		t.Fatalf("Response code is %d, want %d", response.Code, http.StatusUnauthorized)
+	}
+
+	if response := run(http.MethodPut, "/admin/mix", `[{"type":"3"}]`, "token"); response.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Response code is %d, want %d", response.Code, http.StatusUnprocessableEntity)
+	}
+
+	for _, url := range []string{"/admin/mix/preview?count=9000000000000000000", "/admin/mix/preview?count=101", "/admin/mix/preview?offset=10001"} {
+		if response := run(http.MethodPost, url, "", "token"); response.Code != http.StatusBadRequest {
+			t.Fatalf("%s: response code is %d, want %d", url, response.Code, http.StatusBadRequest)
+		}
+	}
+
+	response := run(http.MethodPost, "/admin/mix/preview?count=3&offset=1", `[{"type":"1"},{"type":"2","fallback":"1"}]`, "token")
+	if response.Code != http.StatusOK {
+		t.Fatalf("Response code is %d, want 200", response.Code)
+	}
+
+	sequence := []config.ContentConfig{}
+	if err := json.NewDecoder(response.Body).Decode(&sequence); err != nil {
+		t.Fatalf("couldn't decode response json: %v", err)
+	}
+	if len(sequence) != 3 || sequence[0].Type != provider.Provider2 || sequence[1].Type != provider.Provider1 {
+		t.Fatalf("Got sequence %+v, want [2, 1, 2]", sequence)
+	}
+
+	if response := run(http.MethodPut, "/admin/mix", `[{"type":"2"}]`, "token"); response.Code != http.StatusOK {
+		t.Fatalf("Response code is %d, want 200", response.Code)
+	}
+
+	current := store.CurrentVersion()
+	if current.ID != 2 || current.AppliedBy != "alice" || len(current.Mix) != 1 {
+		t.Fatalf("Got version %+v, want version 2 applied by alice", current)
+	}
+
+	if response := run(http.MethodPost, "/admin/mix/rollback?version=1", "", "token"); response.Code != http.StatusOK {
+		t.Fatalf("Response code is %d, want 200", response.Code)
+	}
+
+	current = store.CurrentVersion()
+	if current.ID != 3 || current.RollbackOf != 1 || len(current.Mix) != len(config.DefaultContentMix) {
+		t.Fatalf("Got version %+v, want version 3 restoring version 1", current)
+	}
+}
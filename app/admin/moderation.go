@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/moderation"
+)
+
+// ModerationHandler shows and replaces the content blocklist.
+//
+//	GET / returns the blocklist
+//	PUT / replaces the blocklist with the JSON body
+type ModerationHandler struct {
+	Moderator *moderation.Moderator
+}
+
+func (h ModerationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, req, http.StatusOK, h.Moderator.Blocklist())
+	case http.MethodPut:
+		blocklist := moderation.Blocklist{}
+		if err := json.NewDecoder(req.Body).Decode(&blocklist); err != nil {
+			writeError(w, req, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		if err := h.Moderator.SetBlocklist(blocklist); err != nil {
+			writeError(w, req, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		log.Printf(
+			"admin: blocklist replaced by %s: %d domains, %d IDs, %d keywords",
+			User(req), len(blocklist.Domains), len(blocklist.IDs), len(blocklist.Keywords),
+		)
+		writeJSON(w, req, http.StatusOK, h.Moderator.Blocklist())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+)
+
+// ScheduleHandler previews which content mix applies at a given time.
+//
+//	GET /?at=2020-09-24T08:00:00Z returns the mix for the given time, or for now if the parameter is omitted
+type ScheduleHandler struct {
+	Schedule *config.Schedule
+	// Store provides the mix used outside of scheduled windows
+	Store *config.Store
+}
+
+type schedulePreview struct {
+	At        time.Time         `json:"at"`
+	Scheduled bool              `json:"scheduled"`
+	Name      string            `json:"name,omitempty"`
+	Mix       config.ContentMix `json:"mix"`
+}
+
+func (h ScheduleHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+
+		return
+	}
+
+	at := time.Now()
+	if value := req.URL.Query().Get("at"); value != "" {
+		var err error
+
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, req, http.StatusBadRequest, "invalid time, expected RFC 3339 format")
+
+			return
+		}
+	}
+
+	preview := schedulePreview{At: at}
+	if h.Schedule != nil {
+		if scheduled := h.Schedule.Active(at); scheduled != nil {
+			preview.Scheduled = true
+			preview.Name = scheduled.Name
+			preview.Mix = scheduled.Mix
+		}
+	}
+	if !preview.Scheduled && h.Store != nil {
+		preview.Mix = h.Store.Current()
+	}
+
+	writeJSON(w, req, http.StatusOK, preview)
+}
@@ -3,27 +3,101 @@ package app
 import (
 	"container/list"
 	"context"
-	"net"
+	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/moderation"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/normalize"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/seen"
 )
 
 const (
 	loadContentTimeout = time.Second * 2
+
+	experimentHeaderName = "X-Experiment-Variant"
+
+	// varyHeaders are request headers the response content depends on
+	varyHeaders = "Accept, X-User-ID, Accept-Language, X-Device-Type, X-App-Version"
+
+	allowedMethods = "GET, HEAD"
 )
 
 type App struct {
 	ContentClients map[provider.Provider]provider.Client
 	Config         config.ContentMix
+	Limiter        *Limiter
+
+	// Budgets are reported by BudgetStats, they're enforced by provider.BudgetedClient wrapping ContentClients
+	Budgets map[provider.Provider]*provider.Budget
+
+	// Mixes overrides Config with a content mix that can be changed at runtime
+	Mixes *config.Store
+	// Schedule picks a content mix by time of day, overriding Mixes and Config
+	Schedule *config.Schedule
+	// Feeds are named content mixes which can be requested with the batch endpoint
+	Feeds config.Feeds
+	// Experiment splits users between alternative content mixes, overriding Schedule, Mixes and Config
+	Experiment *config.Experiment
+	// Normalizer cleans up items received from providers, items are served as they are if nil
+	Normalizer *normalize.Normalizer
+	// Moderator drops blocked items, nothing is blocked if nil
+	Moderator *moderation.Moderator
+	// Serializers are supported response formats, response.DefaultSerializers are used if empty
+	Serializers response.Serializers
+	// Seen suppresses items already served to the user, nothing is suppressed if nil
+	Seen seen.Store
+	// Stream configures the stream of new items served as server-sent events
+	Stream StreamConfig
+	// DebugToken authorises requests for debug output, debug mode is disabled if empty
+	DebugToken string
+}
+
+// providerResult holds content loaded from a provider along with details of the fetch
+type providerResult struct {
+	items     *list.List
+	requested int
+	received  int
+	latency   time.Duration
+	err       error
+	// expired is the number of items dropped because they had already expired
+	expired int
+	// filtered is the number of invalid items and items dropped by moderation and request filters
+	filtered int
+	// suppressed is the number of items skipped because the user has already seen them
+	suppressed int
 }
 
 func (a App) ServeHTTP(w http.ResponseWriter, httpReq *http.Request) {
+	switch httpReq.URL.Path {
+	case "/":
+	case batchPath:
+		a.serveBatch(w, httpReq)
+
+		return
+	case streamPath:
+		a.serveStream(w, httpReq)
+
+		return
+	default:
+		handleError(w, httpReq, ErrNotFound)
+
+		return
+	}
+
+	if httpReq.Method != http.MethodGet && httpReq.Method != http.MethodHead {
+		w.Header().Set("Allow", allowedMethods)
+		handleError(w, httpReq, ErrMethodNotAllowed)
+
+		return
+	}
+
 	// parse request parameters
 	req := &request.Request{}
 	if err := req.Parse(httpReq); err != nil {
@@ -32,38 +106,155 @@ func (a App) ServeHTTP(w http.ResponseWriter, httpReq *http.Request) {
 		return
 	}
 
+	if req.Debug && (a.DebugToken == "" || httpReq.Header.Get(debugTokenHeaderName) != a.DebugToken) {
+		handleError(w, httpReq, ErrDebugForbidden)
+
+		return
+	}
+
+	serializer := a.serializer(*req, httpReq)
+	if serializer == nil {
+		handleError(w, httpReq, ErrNotAcceptable)
+
+		return
+	}
+
+	if a.shedLoad(w, httpReq) {
+		return
+	}
+
 	// load all results from providers
 	// and prepare response
-	resultsPerProvider := a.loadResults(*req)
-	resp := a.prepareResponse(*req, resultsPerProvider)
+	mix, variant := a.contentMix(*req)
+	if variant != "" {
+		w.Header().Set(experimentHeaderName, variant)
+		experimentVariants.Add(variant, 1)
+	}
+
+	resultsPerProvider := a.loadResults(*req, mix)
+	seenItems := a.seenItems(*req, resultsPerProvider)
+	resp, slots := a.prepareResponse(*req, mix, resultsPerProvider, seenItems)
+
+	if req.Debug {
+		handleDebug(w, httpReq, newDebugResponse(resp, slots, resultsPerProvider))
+
+		return
+	}
+
+	w.Header().Add("Vary", varyHeaders)
+	handleSuccess(w, httpReq, serializer, resp, responseFields(*req), a.private(*req, variant))
+
+	// items aren't delivered in response to HEAD requests
+	if httpReq.Method != http.MethodHead {
+		a.recordSeenItems(*req, resp)
+	}
+}
+
+// serializer picks the response format requested with format parameter or Accept header,
+// returns nil if none of the supported formats is acceptable
+func (a App) serializer(req request.Request, httpReq *http.Request) response.Serializer {
+	serializers := a.Serializers
+	if len(serializers) == 0 {
+		serializers = response.DefaultSerializers
+	}
+
+	if req.Format != "" {
+		return serializers.ByFormat(req.Format)
+	}
+
+	return serializers.Negotiate(httpReq.Header.Get("Accept"))
+}
+
+// shedLoad responds with 503 and Retry-After header if the app is overloaded, so requests are rejected early
+// instead of queueing more provider calls. Returns true if the request has been rejected.
+func (a App) shedLoad(w http.ResponseWriter, httpReq *http.Request) bool {
+	if a.Limiter == nil || !a.Limiter.Overloaded() {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(a.Limiter.RetryAfter().Seconds())))
+	handleError(w, httpReq, ErrOverloaded)
+
+	return true
+}
+
+// responseFields returns content item fields to respond with, nil means all of them
+func responseFields(req request.Request) []string {
+	if len(req.Fields) == 0 && req.Legacy {
+		return provider.LegacyItemFields
+	}
+
+	return req.Fields
+}
+
+// private reports whether the response depends on who is asking, so shared caches mustn't store it:
+// the request carries user details, the user is bucketed into an experiment or seen items are suppressed
+func (a App) private(req request.Request, variant string) bool {
+	personalised := req.UserID != "" || req.Locale != "" || req.Device != "" || req.AppVersion != ""
+
+	return personalised || variant != "" || a.Seen != nil
+}
+
+// contentMix returns the content mix to use for a request,
+// and the experiment variant in form of "experiment/variant" if the user is bucketed into one
+func (a App) contentMix(req request.Request) (config.ContentMix, string) {
+	if a.Experiment != nil {
+		if variant := a.Experiment.Assign(userIdentifier(req)); variant != nil {
+			return variant.Mix, a.Experiment.Name + "/" + variant.Name
+		}
+	}
+
+	if a.Schedule != nil {
+		if scheduled := a.Schedule.Active(time.Now()); scheduled != nil {
+			return scheduled.Mix, ""
+		}
+	}
+
+	if a.Mixes != nil {
+		return a.Mixes.Current(), ""
+	}
+
+	return a.Config, ""
+}
 
-	handleSuccess(w, httpReq, resp)
+func (a App) loadResults(req request.Request, mix config.ContentMix) map[provider.Provider]*providerResult {
+	return a.fetchResults(req, providerCounts(req, mix))
 }
 
-func (a App) loadResults(req request.Request) map[provider.Provider]*list.List {
-	// count how many results we need from each provider
-	// including extra results for fallback cases
+// providerCounts counts how many results are needed from each provider
+// including extra results for fallback cases
+func providerCounts(req request.Request, mix config.ContentMix) map[provider.Provider]int {
 	resPerProvider := make(map[provider.Provider]int)
 	for i := int(req.Offset); i < int(req.Count+req.Offset); i++ {
-		providerType := a.Config[i%len(a.Config)].Type
+		providerType := mix[i%len(mix)].Type
 		resPerProvider[providerType]++
 
-		fallbackProviderType := a.Config[i%len(a.Config)].Fallback
+		fallbackProviderType := mix[i%len(mix)].Fallback
 		if fallbackProviderType != nil {
 			resPerProvider[*fallbackProviderType]++
 		}
 	}
 
+	return resPerProvider
+}
+
+// fetchResults loads the given number of results from each provider
+func (a App) fetchResults(req request.Request, resPerProvider map[provider.Provider]int) map[provider.Provider]*providerResult {
 	// fetch results from all providers simultaneously
 	wg := &sync.WaitGroup{}
-	contentPerProvider := make(map[provider.Provider]*list.List)
+	contentPerProvider := make(map[provider.Provider]*providerResult)
 
 	for i := range resPerProvider {
 		providerType := i
 
-		if contentPerProvider[providerType] == nil {
-			contentPerProvider[providerType] = list.New()
+		result := &providerResult{
+			items:     list.New(),
+			requested: resPerProvider[providerType],
 		}
+		if isFiltered(req) {
+			result.requested *= filteredOverFetchFactor
+		}
+		contentPerProvider[providerType] = result
 
 		wg.Add(1)
 		go func() {
@@ -72,10 +263,28 @@ func (a App) loadResults(req request.Request) map[provider.Provider]*list.List {
 			ctx, cancel := context.WithTimeout(context.Background(), loadContentTimeout)
 			defer cancel()
 
-			// ignore errors, will rely on empty result list
-			res, _ := getContentWithTimeout(ctx, a.ContentClients[providerType], req.UserIP, resPerProvider[providerType])
+			// errors are kept for debugging only, will rely on empty result list
+			started := time.Now()
+			res, err := a.getContentWithTimeout(ctx, providerType, contentRequest(req, result.requested))
+
+			result.latency = time.Since(started)
+			result.received = len(res)
+			result.err = err
+
+			// provider is treated as failed when its budget is exhausted,
+			// so the configured fallback is used instead
+			if err == provider.ErrBudgetExhausted {
+				budgetExhausted.Add(string(providerType), 1)
+			}
+
+			// items which expired before the call was made are stale, even if the provider still returns them
+			res, result.expired = dropExpired(res, started)
+
+			res = a.filterItems(req, providerType, res)
+			result.filtered = result.received - result.expired - len(res)
+
 			for j := range res {
-				contentPerProvider[providerType].PushFront(res[j])
+				result.items.PushFront(res[j])
 			}
 		}()
 	}
@@ -85,26 +294,38 @@ func (a App) loadResults(req request.Request) map[provider.Provider]*list.List {
 	return contentPerProvider
 }
 
-func (a App) prepareResponse(req request.Request, resultsPerProvider map[provider.Provider]*list.List) response.Response {
+func (a App) prepareResponse(
+	req request.Request,
+	mix config.ContentMix,
+	resultsPerProvider map[provider.Provider]*providerResult,
+	seenItems map[string]bool,
+) (response.Response, []slot) {
 	resp := make(response.Response, 0, req.Count)
+	slots := make([]slot, 0, req.Count)
 
 	for i := int(req.Offset); i < int(req.Count+req.Offset); i++ {
-		providerType := a.Config[i%len(a.Config)].Type
-		fallbackProviderType := a.Config[i%len(a.Config)].Fallback
+		providerType := mix[i%len(mix)].Type
+		fallbackProviderType := mix[i%len(mix)].Fallback
 
-		el := resultsPerProvider[providerType].Back()
-		if el != nil {
-			resultsPerProvider[providerType].Remove(el)
+		s := slot{
+			Position:    i,
+			ConfigIndex: i % len(mix),
+			Provider:    providerType,
+			Fallback:    fallbackProviderType,
 		}
 
-		if el == nil && fallbackProviderType != nil {
-			el = resultsPerProvider[*fallbackProviderType].Back()
+		el := resultsPerProvider[providerType].takeItem(seenItems)
+		if el == nil {
+			s.Reason = resultsPerProvider[providerType].failureReason()
+		}
 
-			if el != nil {
-				resultsPerProvider[*fallbackProviderType].Remove(el)
-			}
+		if el == nil && fallbackProviderType != nil {
+			s.FallbackUsed = true
+			el = resultsPerProvider[*fallbackProviderType].takeItem(seenItems)
 		}
 
+		slots = append(slots, s)
+
 		if el == nil {
 			break
 		}
@@ -112,24 +333,112 @@ func (a App) prepareResponse(req request.Request, resultsPerProvider map[provide
 		resp = append(resp, *el.Value.(*provider.ContentItem))
 	}
 
-	return resp
+	return resp, slots
+}
+
+// takeItem removes and returns the next item the user hasn't seen yet, returns nil if there are none left
+func (r *providerResult) takeItem(seenItems map[string]bool) *list.Element {
+	for el := r.items.Back(); el != nil; el = r.items.Back() {
+		r.items.Remove(el)
+
+		if !seenItems[el.Value.(*provider.ContentItem).ID] {
+			return el
+		}
+
+		r.suppressed++
+	}
+
+	return nil
 }
 
-func getContentWithTimeout(ctx context.Context, client provider.Client, userIP net.IP, count int) ([]*provider.ContentItem, error) {
-	done := make(chan struct{}, 1)
+// seenItems returns IDs of loaded items already served to the user
+func (a App) seenItems(req request.Request, resultsPerProvider map[provider.Provider]*providerResult) map[string]bool {
+	if a.Seen == nil {
+		return nil
+	}
+
+	var ids []string
+	for _, result := range resultsPerProvider {
+		for el := result.items.Front(); el != nil; el = el.Next() {
+			ids = append(ids, el.Value.(*provider.ContentItem).ID)
+		}
+	}
+
+	// ignore errors, showing an item twice is better than not responding
+	seenItems, err := a.Seen.Seen(userIdentifier(req), ids)
+	if err != nil {
+		log.Printf("couldn't load seen items: %v", err)
+	}
+
+	return seenItems
+}
+
+// recordSeenItems remembers items served to the user, so they are suppressed on the next pages
+func (a App) recordSeenItems(req request.Request, resp response.Response) {
+	if a.Seen == nil || len(resp) == 0 {
+		return
+	}
+
+	ids := make([]string, len(resp))
+	for i := range resp {
+		ids[i] = resp[i].ID
+	}
+
+	if err := a.Seen.Add(userIdentifier(req), ids); err != nil {
+		log.Printf("couldn't record seen items: %v", err)
+	}
+}
+
+func (a App) getContentWithTimeout(ctx context.Context, providerType provider.Provider, contentReq provider.ContentRequest) ([]*provider.ContentItem, error) {
+	release := func() {}
+	if a.Limiter != nil {
+		var err error
+
+		release, err = a.Limiter.acquire(ctx, providerType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type result struct {
+		res []*provider.ContentItem
+		err error
+	}
 
-	var res []*provider.ContentItem
-	var err error
+	// buffered, so the goroutine can always finish once the client returns
+	done := make(chan result, 1)
 
 	go func() {
-		res, err = client.GetContent(userIP.String(), count)
-		done <- struct{}{}
+		defer release()
+
+		res, err := provider.GetContent(a.ContentClients[providerType], contentReq)
+		done <- result{res: res, err: err}
 	}()
 
 	select {
-	case <-done:
-		return res, err
+	case r := <-done:
+		return r.res, r.err
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
+
+func contentRequest(req request.Request, count int) provider.ContentRequest {
+	return provider.ContentRequest{
+		UserIP:     req.UserIP.String(),
+		Count:      count,
+		UserID:     req.UserID,
+		Locale:     req.Locale,
+		Device:     req.Device,
+		AppVersion: req.AppVersion,
+	}
+}
+
+// userIdentifier identifies the user by ID, or by IP for anonymous users
+func userIdentifier(req request.Request) string {
+	if req.UserID != "" {
+		return req.UserID
+	}
+
+	return req.UserIP.String()
+}
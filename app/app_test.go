@@ -1,15 +1,24 @@
 package app
 
 import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/seen"
 )
 
 var (
@@ -202,6 +211,944 @@ func TestFallback_ClientReturnsError(t *testing.T) {
 	}
 }
 
+func TestFallback_BudgetExhausted(t *testing.T) {
+	budget := provider.NewBudget(0, 1)
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider.NewBudgetedClient(&provider.ContentProviderMock{Source: provider.Provider1}, budget),
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+		Budgets: map[provider.Provider]*provider.Budget{
+			provider.Provider1: budget,
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=1", nil)
+
+	content := runRequest(t, handler, req)
+	if len(content) != 1 || provider.Provider(content[0].Source) != provider.Provider1 {
+		t.Fatalf("Got %v, want 1 item from Provider %v", content, provider.Provider1)
+	}
+
+	content = runRequest(t, handler, req)
+	if len(content) != 1 || provider.Provider(content[0].Source) != provider.Provider2 {
+		t.Fatalf("Got %v, want 1 item from Provider %v", content, provider.Provider2)
+	}
+}
+
+func TestFallback_ChaosErrors(t *testing.T) {
+	provider1Client := provider.NewChaosClient(&provider.ContentProviderMock{Source: provider.Provider1})
+	if err := provider1Client.SetConfig(provider.ChaosConfig{ErrorRate: 1}); err != nil {
+		t.Fatalf("couldn't set chaos config: %v", err)
+	}
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider1Client,
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=3", nil)
+	content := runRequest(t, handler, req)
+
+	if len(content) != 3 {
+		t.Fatalf("Got %d items back, want 3", len(content))
+	}
+
+	for i := range content {
+		if provider.Provider(content[i].Source) != provider.Provider2 {
+			t.Errorf("Got Provider %v instead of Provider %v", content[i].Source, provider.Provider2)
+		}
+	}
+}
+
+func TestFallback_ReplayedTraffic(t *testing.T) {
+	provider1Client, err := provider.LoadReplayClient("testdata/provider_traffic.jsonl", provider.Provider1)
+	if err != nil {
+		t.Fatalf("couldn't load recorded traffic: %v", err)
+	}
+	provider2Client, err := provider.LoadReplayClient("testdata/provider_traffic.jsonl", provider.Provider2)
+	if err != nil {
+		t.Fatalf("couldn't load recorded traffic: %v", err)
+	}
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider1Client,
+			provider.Provider2: provider2Client,
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+	}
+
+	for _, expectedID := range []string{"article-1", "article-3"} {
+		req := httptest.NewRequest(http.MethodGet, "/?count=1", nil)
+		content := runRequest(t, handler, req)
+
+		if len(content) != 1 {
+			t.Fatalf("Got %d items back, want 1", len(content))
+		}
+
+		if content[0].ID != expectedID {
+			t.Errorf("Got ID %v instead of ID %v", content[0].ID, expectedID)
+		}
+	}
+}
+
+func TestDebugMode(t *testing.T) {
+	provider1Client := &provider.ContentProviderMock{Source: provider.Provider1}
+	provider1Client.SetError(errors.New("expected error"))
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider1Client,
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+		DebugToken: "secret",
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/?count=2&debug=1", nil))
+
+	if response.Code != http.StatusForbidden {
+		t.Fatalf("Response code is %d, want %d", response.Code, http.StatusForbidden)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=2&debug=1", nil)
+	req.Header.Set(debugTokenHeaderName, "secret")
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("Response code is %d, want 200", response.Code)
+	}
+
+	debug := debugResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&debug); err != nil {
+		t.Fatalf("couldn't decode response json: %v", err)
+	}
+
+	if len(debug.Items) != 2 || len(debug.Slots) != 2 {
+		t.Fatalf("Got %d items and %d slots back, want 2", len(debug.Items), len(debug.Slots))
+	}
+
+	for _, s := range debug.Slots {
+		if !s.FallbackUsed || s.Reason != reasonError || !s.Filled {
+			t.Errorf("Position %d: got %+v, want filled by fallback because of an error", s.Position, s)
+		}
+	}
+
+	if debug.Providers[provider.Provider1].Error != "expected error" {
+		t.Errorf("Got Provider %v error '%v', want 'expected error'", provider.Provider1, debug.Providers[provider.Provider1].Error)
+	}
+	if debug.Providers[provider.Provider2].Received != 2 {
+		t.Errorf("Got %d items from Provider %v, want 2", debug.Providers[provider.Provider2].Received, provider.Provider2)
+	}
+}
+
+func TestDebugMode_ExpiredItems(t *testing.T) {
+	provider1Client := &provider.ContentProviderMock{Source: provider.Provider1}
+	provider1Client.SetResponse([]*provider.ContentItem{
+		{ID: "1", Title: "title", Source: "1", Expiry: time.Now().Add(-time.Minute)},
+	})
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider1Client,
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+		DebugToken: "secret",
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=1&debug=1", nil)
+	req.Header.Set(debugTokenHeaderName, "secret")
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	debug := debugResponse{}
+	if err := json.NewDecoder(response.Body).Decode(&debug); err != nil {
+		t.Fatalf("couldn't decode response json: %v", err)
+	}
+
+	if len(debug.Slots) != 1 || !debug.Slots[0].FallbackUsed || debug.Slots[0].Reason != reasonExpired {
+		t.Fatalf("Got slots %+v, want a slot filled by fallback because of expired items", debug.Slots)
+	}
+	if debug.Providers[provider.Provider1].Expired != 1 {
+		t.Errorf("Got %d expired items from Provider %v, want 1", debug.Providers[provider.Provider1].Expired, provider.Provider1)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	// the first window starts in an hour, the second one contains every hour
+	hour := time.Now().UTC().Hour()
+
+	handler := defaultHandler
+	handler.Schedule = &config.Schedule{
+		Mixes: []config.ScheduledMix{
+			{
+				Name:   "later",
+				Window: config.Window{StartHour: (hour + 1) % 24, EndHour: (hour + 2) % 24},
+				Mix:    config.ContentMix{config.ContentConfig{Type: provider.Provider1}},
+			},
+			{
+				Name:   "all day",
+				Window: config.Window{StartHour: 0, EndHour: 24},
+				Mix:    config.ContentMix{config.ContentConfig{Type: provider.Provider3}},
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=5", nil)
+	content := runRequest(t, handler, req)
+
+	if len(content) != 5 {
+		t.Fatalf("Got %d items back, want 5", len(content))
+	}
+
+	for i, item := range content {
+		if provider.Provider(item.Source) != provider.Provider3 {
+			t.Errorf("Position %d: Got Provider %v instead of Provider %v", i, item.Source, provider.Provider3)
+		}
+	}
+}
+
+func TestExperiment(t *testing.T) {
+	handler := defaultHandler
+	handler.Experiment = &config.Experiment{
+		Name: "test",
+		Variants: []config.Variant{
+			{Name: "a", Weight: 1, Mix: config.ContentMix{config.ContentConfig{Type: provider.Provider1}}},
+			{Name: "b", Weight: 1, Mix: config.ContentMix{config.ContentConfig{Type: provider.Provider2}}},
+		},
+	}
+
+	expectedSource := map[string]provider.Provider{
+		"test/a": provider.Provider1,
+		"test/b": provider.Provider2,
+	}
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"} {
+		variant := ""
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/?count=1", nil)
+			req.RemoteAddr = ip + ":80"
+
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+
+			assigned := response.Header().Get(experimentHeaderName)
+			if _, ok := expectedSource[assigned]; !ok {
+				t.Fatalf("IP %s: got unexpected variant '%v'", ip, assigned)
+			}
+			if variant != "" && assigned != variant {
+				t.Fatalf("IP %s: got variant '%v', previously assigned '%v'", ip, assigned, variant)
+			}
+			variant = assigned
+
+			content := []*provider.ContentItem{}
+			if err := json.NewDecoder(response.Body).Decode(&content); err != nil {
+				t.Fatalf("couldn't decode response json: %v", err)
+			}
+			if len(content) != 1 || provider.Provider(content[0].Source) != expectedSource[variant] {
+				t.Errorf("IP %s: got %v, want 1 item from Provider %v", ip, content, expectedSource[variant])
+			}
+		}
+	}
+}
+
+func TestPersonalisedRequest(t *testing.T) {
+	providerClient := &provider.ContentProviderMock{Source: provider.Provider1}
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{provider.Provider1: providerClient},
+		Config:         config.ContentMix{config.ContentConfig{Type: provider.Provider1}},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=2&user_id=user-1&locale=en-GB&device=phone&app_version=1.2", nil)
+	runRequest(t, handler, req)
+
+	expected := provider.ContentRequest{
+		UserIP:     "192.0.2.1",
+		Count:      2,
+		UserID:     "user-1",
+		Locale:     "en-GB",
+		Device:     "phone",
+		AppVersion: "1.2",
+	}
+	if providerClient.LastRequest() != expected {
+		t.Errorf("Got request %+v instead of %+v", providerClient.LastRequest(), expected)
+	}
+}
+
+func TestSeenItemsSuppressed(t *testing.T) {
+	providerClient := &provider.ContentProviderMock{Source: provider.Provider1}
+	providerClient.SetResponse([]*provider.ContentItem{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}})
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{provider.Provider1: providerClient},
+		Config:         config.ContentMix{config.ContentConfig{Type: provider.Provider1}},
+		Seen:           seen.NewMemoryStore(time.Minute),
+	}
+
+	for _, expectedIDs := range [][]string{{"1", "2"}, {"3", "4"}, {}} {
+		req := httptest.NewRequest(http.MethodGet, "/?count=2&user_id=user-1", nil)
+		content := runRequest(t, handler, req)
+
+		if len(content) != len(expectedIDs) {
+			t.Fatalf("Got %d items back, want %d", len(content), len(expectedIDs))
+		}
+
+		for i := range content {
+			if content[i].ID != expectedIDs[i] {
+				t.Errorf("Got ID %v instead of ID %v", content[i].ID, expectedIDs[i])
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=2&user_id=user-2", nil)
+	if content := runRequest(t, handler, req); len(content) != 2 {
+		t.Fatalf("Got %d items back for another user, want 2", len(content))
+	}
+}
+
+func TestFallback_CategoriesFiltered(t *testing.T) {
+	provider1Client := &provider.ContentProviderMock{Source: provider.Provider1}
+	provider1Client.SetResponse([]*provider.ContentItem{
+		{ID: "1", Source: string(provider.Provider1), Categories: []string{"Politics"}},
+		{ID: "2", Source: string(provider.Provider1), Categories: []string{"sports"}},
+		{ID: "3", Source: string(provider.Provider1), Categories: []string{"politics", "sports"}},
+	})
+	provider2Client := &provider.ContentProviderMock{Source: provider.Provider2}
+	provider2Client.SetResponse([]*provider.ContentItem{
+		{ID: "4", Source: string(provider.Provider2), Categories: []string{"sports"}},
+		{ID: "5", Source: string(provider.Provider2), Categories: []string{"sports"}},
+	})
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: provider1Client,
+			provider.Provider2: provider2Client,
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=3&include=sports&exclude=politics", nil)
+	content := runRequest(t, handler, req)
+
+	expectedIDs := []string{"2", "4", "5"}
+	if len(content) != len(expectedIDs) {
+		t.Fatalf("Got %d items back, want %d", len(content), len(expectedIDs))
+	}
+
+	for i := range content {
+		if content[i].ID != expectedIDs[i] {
+			t.Errorf("Got ID %v instead of ID %v", content[i].ID, expectedIDs[i])
+		}
+	}
+}
+
+func TestResponseFields(t *testing.T) {
+	for _, test := range []struct {
+		url            string
+		expectedFields int
+		expectedImages bool
+	}{
+		{url: "/?count=2", expectedFields: 11, expectedImages: true},
+		{url: "/?count=2&shape=legacy", expectedFields: 6, expectedImages: false},
+		{url: "/?count=2&fields=id,title,link", expectedFields: 3, expectedImages: false},
+		{url: "/?count=2&fields=id,images", expectedFields: 2, expectedImages: true},
+	} {
+		response := httptest.NewRecorder()
+		defaultHandler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+		content := []map[string]interface{}{}
+		if err := json.NewDecoder(response.Body).Decode(&content); err != nil {
+			t.Fatalf("couldn't decode response json: %v", err)
+		}
+
+		if len(content) != 2 {
+			t.Fatalf("%s: got %d items back, want 2", test.url, len(content))
+		}
+
+		for _, item := range content {
+			if len(item) != test.expectedFields {
+				t.Errorf("%s: got %d fields, want %d", test.url, len(item), test.expectedFields)
+			}
+			if _, ok := item["id"]; !ok {
+				t.Errorf("%s: item ID is missing", test.url)
+			}
+			if _, ok := item["images"]; ok != test.expectedImages {
+				t.Errorf("%s: got item images %v, want %v", test.url, ok, test.expectedImages)
+			}
+		}
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	testCases := []struct {
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+	}{
+		{url: "/", expectedCode: http.StatusOK, expectedContentType: "application/json"},
+		{url: "/", accept: "text/csv", expectedCode: http.StatusOK, expectedContentType: "text/csv"},
+		{url: "/?format=xml", accept: "text/csv", expectedCode: http.StatusOK, expectedContentType: "application/xml"},
+		{url: "/?format=ndjson", expectedCode: http.StatusOK, expectedContentType: "application/x-ndjson"},
+		{url: "/", accept: "text/html", expectedCode: http.StatusNotAcceptable},
+		{url: "/?format=yaml", expectedCode: http.StatusNotAcceptable},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("Accept", test.accept)
+
+		response := httptest.NewRecorder()
+		defaultHandler.ServeHTTP(response, req)
+
+		if response.Code != test.expectedCode {
+			t.Errorf("%s, Accept %s: response code is %d, want %d", test.url, test.accept, response.Code, test.expectedCode)
+		}
+		if contentType := response.Header().Get("Content-Type"); test.expectedContentType != "" && contentType != test.expectedContentType {
+			t.Errorf("%s, Accept %s: content type is %s, want %s", test.url, test.accept, contentType, test.expectedContentType)
+		}
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	limiter := NewLimiter(1, 1, 0)
+
+	release, err := limiter.acquire(context.Background(), provider.Provider1)
+	if err != nil {
+		t.Fatalf("couldn't acquire a slot: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the call to another provider gets its provider slot, and waits for the global one
+	go limiter.acquire(ctx, provider.Provider2)
+	for !limiter.Overloaded() {
+		time.Sleep(time.Millisecond)
+	}
+
+	handler := defaultHandler
+	handler.Limiter = limiter
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Response code is %d, want %d", response.Code, http.StatusServiceUnavailable)
+	}
+	if response.Header().Get("Retry-After") == "" {
+		t.Errorf("Retry-After header is missing")
+	}
+}
+
+func TestLoadShedding_ProviderQueue(t *testing.T) {
+	limiter := NewLimiter(10, 1, 0)
+
+	// provider 1 hangs with its only slot taken
+	release, err := limiter.acquire(context.Background(), provider.Provider1)
+	if err != nil {
+		t.Fatalf("couldn't acquire a slot: %v", err)
+	}
+	defer release()
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: &provider.ContentProviderMock{Source: provider.Provider1},
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+		},
+		Config: config.ContentMix{
+			config.ContentConfig{
+				Type:     provider.Provider1,
+				Fallback: &provider.Provider2,
+			},
+		},
+		Limiter: limiter,
+	}
+
+	started := time.Now()
+	content := runRequest(t, handler, httptest.NewRequest(http.MethodGet, "/?count=2", nil))
+
+	if elapsed := time.Since(started); elapsed >= loadContentTimeout {
+		t.Errorf("Request took %v, want the call to provider 1 to fail fast", elapsed)
+	}
+	if len(content) != 2 {
+		t.Fatalf("Got %d items back, want 2", len(content))
+	}
+	for i := range content {
+		if provider.Provider(content[i].Source) != provider.Provider2 {
+			t.Errorf("Got Provider %v instead of Provider %v", content[i].Source, provider.Provider2)
+		}
+	}
+	if limiter.Overloaded() {
+		t.Errorf("Limiter is overloaded by a single provider")
+	}
+	if reason := (&providerResult{err: ErrProviderOverloaded}).failureReason(); reason != reasonOverloaded {
+		t.Errorf("Got failure reason %q, want %q", reason, reasonOverloaded)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	testCases := []struct {
+		url              string
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{url: "/?count=100", acceptEncoding: "gzip, deflate", expectedEncoding: "gzip"},
+		{url: "/?count=100", acceptEncoding: "deflate, gzip;q=0.5", expectedEncoding: "deflate"},
+		{url: "/?count=100", acceptEncoding: "gzip;q=0", expectedEncoding: ""},
+		{url: "/?count=100", acceptEncoding: "gzip;q=0, *", expectedEncoding: "deflate"},
+		{url: "/?count=100", acceptEncoding: "gzip;q=0, deflate;q=0, *", expectedEncoding: ""},
+		{url: "/?count=100", acceptEncoding: "*", expectedEncoding: "gzip"},
+		{url: "/?count=100", expectedEncoding: ""},
+		{url: "/?count=1", acceptEncoding: "gzip", expectedEncoding: ""},
+	}
+
+	handler := Compress(defaultHandler, 1024)
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		if encoding := response.Header().Get("Content-Encoding"); encoding != test.expectedEncoding {
+			t.Errorf("%s, Accept-Encoding %s: content encoding is %q, want %q", test.url, test.acceptEncoding, encoding, test.expectedEncoding)
+		}
+		if vary := response.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s, Accept-Encoding %s: Vary is %q, want Accept-Encoding", test.url, test.acceptEncoding, vary)
+		}
+
+		var body io.Reader = response.Body
+		switch test.expectedEncoding {
+		case "gzip":
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				t.Fatalf("%s: couldn't read gzip body: %v", test.url, err)
+			}
+			body = reader
+		case "deflate":
+			reader, err := zlib.NewReader(body)
+			if err != nil {
+				t.Fatalf("%s: couldn't read zlib body: %v", test.url, err)
+			}
+			body = reader
+		}
+
+		var content []*provider.ContentItem
+		if err := json.NewDecoder(body).Decode(&content); err != nil {
+			t.Fatalf("%s, Accept-Encoding %s: couldn't decode body: %v", test.url, test.acceptEncoding, err)
+		}
+	}
+}
+
+func TestConditionalRequest(t *testing.T) {
+	expiry := time.Now().Add(time.Minute)
+	clients := make(map[provider.Provider]provider.Client)
+	for _, providerType := range []provider.Provider{provider.Provider1, provider.Provider2, provider.Provider3} {
+		client := &provider.ContentProviderMock{Source: providerType}
+		client.SetResponse([]*provider.ContentItem{
+			{ID: "1", Title: "title", Source: string(providerType), Link: "https://example.com/1", Expiry: expiry},
+			{ID: "2", Title: "title", Source: string(providerType), Link: "https://example.com/2", Expiry: expiry.Add(time.Hour)},
+		})
+		clients[providerType] = client
+	}
+
+	handler := App{
+		ContentClients: clients,
+		Config:         config.DefaultContentMix,
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/?count=3", nil))
+
+	etag := response.Header().Get("ETag")
+	if response.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Got response code %d and ETag %q, want 200 and an ETag", response.Code, etag)
+	}
+	if cacheControl := response.Header().Get("Cache-Control"); cacheControl != "max-age=59" && cacheControl != "max-age=60" {
+		t.Errorf("Got Cache-Control %q, want max-age=59", cacheControl)
+	}
+	if vary := response.Header().Get("Vary"); vary != varyHeaders {
+		t.Errorf("Got Vary %q, want %q", vary, varyHeaders)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=3", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	if response.Code != http.StatusNotModified {
+		t.Errorf("Got response code %d for matching ETag, want 304", response.Code)
+	}
+	if response.Body.Len() != 0 {
+		t.Errorf("Got %d bytes of body for matching ETag, want none", response.Body.Len())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?count=3", nil)
+	req.Header.Set("If-None-Match", `"other"`)
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	if response.Code != http.StatusOK {
+		t.Errorf("Got response code %d for different ETag, want 200", response.Code)
+	}
+}
+
+func TestPrivateCaching(t *testing.T) {
+	testCases := []struct {
+		name     string
+		handler  App
+		url      string
+		expected bool
+	}{
+		{name: "Anonymous", handler: defaultHandler, url: "/", expected: false},
+		{name: "User details", handler: defaultHandler, url: "/?user_id=user-1", expected: true},
+		{name: "Locale", handler: defaultHandler, url: "/?locale=en-GB", expected: true},
+		{
+			name:     "Seen suppression",
+			handler:  App{ContentClients: defaultHandler.ContentClients, Config: defaultHandler.Config, Seen: seen.NewMemoryStore(time.Minute)},
+			url:      "/",
+			expected: true,
+		},
+	}
+
+	for _, test := range testCases {
+		response := httptest.NewRecorder()
+		test.handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, test.url, nil))
+
+		cacheControl := response.Header().Get("Cache-Control")
+		if private := strings.HasPrefix(cacheControl, "private, max-age="); private != test.expected {
+			t.Errorf("%s: got Cache-Control %q, want private %v", test.name, cacheControl, test.expected)
+		}
+	}
+}
+
+func TestRouting(t *testing.T) {
+	testCases := []struct {
+		method         string
+		url            string
+		expectedCode   int
+		expectedError  string
+		expectedParam  string
+		expectedErrors int
+	}{
+		{method: http.MethodGet, url: "/", expectedCode: http.StatusOK},
+		{method: http.MethodHead, url: "/", expectedCode: http.StatusOK},
+		{method: http.MethodPost, url: "/", expectedCode: http.StatusMethodNotAllowed, expectedError: codeMethodNotAllowed},
+		{method: http.MethodGet, url: "/items", expectedCode: http.StatusNotFound, expectedError: codeNotFound},
+		{method: http.MethodGet, url: "/?count=101", expectedCode: http.StatusBadRequest, expectedError: codeInvalidParameter, expectedParam: "count"},
+		{method: http.MethodGet, url: "/?offset=-1", expectedCode: http.StatusBadRequest, expectedError: codeInvalidParameter, expectedParam: "offset"},
+		{method: http.MethodGet, url: "/?count=101&offset=-1", expectedCode: http.StatusBadRequest, expectedError: codeInvalidParameter, expectedParam: "count", expectedErrors: 2},
+	}
+
+	for _, test := range testCases {
+		response := httptest.NewRecorder()
+		defaultHandler.ServeHTTP(response, httptest.NewRequest(test.method, test.url, nil))
+
+		if response.Code != test.expectedCode {
+			t.Errorf("%s %s: response code is %d, want %d", test.method, test.url, response.Code, test.expectedCode)
+		}
+		if test.method == http.MethodHead && response.Body.Len() != 0 {
+			t.Errorf("%s %s: got %d bytes of body, want none", test.method, test.url, response.Body.Len())
+		}
+		if test.expectedCode == http.StatusMethodNotAllowed && response.Header().Get("Allow") != allowedMethods {
+			t.Errorf("%s %s: Allow header is %q, want %q", test.method, test.url, response.Header().Get("Allow"), allowedMethods)
+		}
+
+		if test.expectedError == "" {
+			continue
+		}
+
+		var body errorResponse
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatalf("%s %s: couldn't decode error body: %v", test.method, test.url, err)
+		}
+		if body.Code != test.expectedError || body.Param != test.expectedParam {
+			t.Errorf("%s %s: got error %s for param %q, want %s for param %q", test.method, test.url, body.Code, body.Param, test.expectedError, test.expectedParam)
+		}
+		if test.expectedErrors > 0 && len(body.Errors) != test.expectedErrors {
+			t.Errorf("%s %s: got %d validation errors, want %d", test.method, test.url, len(body.Errors), test.expectedErrors)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	provider3Client := &provider.ContentProviderMock{Source: provider.Provider3}
+
+	handler := App{
+		ContentClients: map[provider.Provider]provider.Client{
+			provider.Provider1: &provider.ContentProviderMock{Source: provider.Provider1},
+			provider.Provider2: &provider.ContentProviderMock{Source: provider.Provider2},
+			provider.Provider3: provider3Client,
+		},
+		Config: config.DefaultContentMix,
+		Feeds: config.Feeds{
+			"sports": config.ContentMix{config.ContentConfig{Type: provider.Provider3}},
+		},
+	}
+
+	body := strings.NewReader(`[
+		{"feed": "default", "count": 3},
+		{"feed": "sports", "count": 2, "offset": 1},
+		{"feed": "weather", "count": 2},
+		{"feed": "sports", "count": 101}
+	]`)
+	req := httptest.NewRequest(http.MethodPost, "/batch", body)
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("Got response code %d, want 200", response.Code)
+	}
+
+	var results []struct {
+		Feed  string                  `json:"feed"`
+		Items []*provider.ContentItem `json:"items"`
+		Error *errorResponse          `json:"error"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&results); err != nil {
+		t.Fatalf("Couldn't decode batch response: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Got %d batch results back, want 4", len(results))
+	}
+	if len(results[0].Items) != 3 || results[0].Error != nil {
+		t.Errorf("Got %d items and error %+v for the default feed, want 3 items", len(results[0].Items), results[0].Error)
+	}
+	if len(results[1].Items) != 2 || results[1].Error != nil {
+		t.Errorf("Got %d items and error %+v for the sports feed, want 2 items", len(results[1].Items), results[1].Error)
+	}
+	for _, item := range results[1].Items {
+		if provider.Provider(item.Source) != provider.Provider3 {
+			t.Errorf("Got item of provider %s in the sports feed, want %s", item.Source, provider.Provider3)
+		}
+	}
+	if results[2].Error == nil || results[2].Error.Code != codeUnknownFeed {
+		t.Errorf("Got error %+v for an unknown feed, want %s", results[2].Error, codeUnknownFeed)
+	}
+	if results[3].Error == nil || results[3].Error.Param != "count" {
+		t.Errorf("Got error %+v for an invalid count, want invalid count", results[3].Error)
+	}
+
+	// provider 3 is the fallback of one item in the default feed, and serves both items of the sports feed
+	if count := provider3Client.LastRequest().Count; count != 3 {
+		t.Errorf("Provider 3 was asked for %d items, want 3 in a single call", count)
+	}
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[]`)))
+
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Got response code %d for an empty batch, want 400", response.Code)
+	}
+}
+
+func TestStream_Validation(t *testing.T) {
+	handler := defaultHandler
+	handler.Stream = StreamConfig{MaxBuffered: 5}
+
+	testCases := []struct {
+		url           string
+		lastEventID   string
+		expectedCode  int
+		expectedParam string
+	}{
+		// Last-Event-ID means nothing outside of streams
+		{url: "/", lastEventID: "last", expectedCode: http.StatusOK},
+		{url: "/stream", lastEventID: "last", expectedCode: http.StatusBadRequest, expectedParam: "Last-Event-ID"},
+		{url: "/stream?count=6", expectedCode: http.StatusBadRequest, expectedParam: "count"},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", test.lastEventID)
+		}
+
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, req)
+
+		if response.Code != test.expectedCode {
+			t.Fatalf("%s: got response code %d, want %d", test.url, response.Code, test.expectedCode)
+		}
+		if test.expectedParam == "" {
+			continue
+		}
+
+		var body errorResponse
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: couldn't decode error: %v", test.url, err)
+		}
+		if body.Code != codeInvalidParameter || body.Param != test.expectedParam {
+			t.Errorf("%s: got error %s for param %q, want %s for param %q", test.url, body.Code, body.Param, codeInvalidParameter, test.expectedParam)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	handler := defaultHandler
+	handler.Stream = StreamConfig{Interval: time.Millisecond * 50, Heartbeat: time.Millisecond * 20}
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/stream?count=2", nil)
+	if err != nil {
+		t.Fatalf("Couldn't create request: %v", err)
+	}
+	req.Header.Set("Last-Event-ID", "9")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Couldn't connect to the stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Got content type %s, want text/event-stream", contentType)
+	}
+
+	// the stream resumes after the last event, and keeps following the content mix on the next poll
+	var ids []string
+	heartbeat := false
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < 4 && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			var item provider.ContentItem
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &item); err != nil {
+				t.Fatalf("Couldn't decode event data: %v", err)
+			}
+
+			position := 10 + len(ids) - 1
+			if want := defaultHandler.Config[position%len(defaultHandler.Config)].Type; provider.Provider(item.Source) != want {
+				t.Errorf("Position %d: Got Provider %v instead of Provider %v", position, item.Source, want)
+			}
+		case line == ": heartbeat":
+			heartbeat = true
+		}
+	}
+
+	if strings.Join(ids, ",") != "10,11,12,13" {
+		t.Errorf("Got event IDs %v, want 10,11,12,13", ids)
+	}
+	if !heartbeat {
+		t.Errorf("Got no heartbeat between polls")
+	}
+}
+
+func TestStream_SlowClient(t *testing.T) {
+	req := request.NewRequest(2, 0, net.ParseIP("127.0.0.1"))
+	events := make(chan streamEvent, 1)
+
+	err := defaultHandler.pollItems(context.Background(), *req, defaultHandler.Config, 0, time.Minute, events)
+	if err != errSlowClient {
+		t.Fatalf("Got error %v, want %v", err, errSlowClient)
+	}
+}
+
+func TestCompression_ETag(t *testing.T) {
+	client := &provider.ContentProviderMock{Source: provider.Provider1}
+	client.SetResponse([]*provider.ContentItem{
+		{ID: "1", Title: "title", Source: "1", Link: "https://example.com/1", Expiry: time.Now().Add(time.Minute)},
+	})
+
+	handler := Compress(App{
+		ContentClients: map[provider.Provider]provider.Client{provider.Provider1: client},
+		Config:         config.ContentMix{config.ContentConfig{Type: provider.Provider1}},
+	}, 1)
+
+	identity := httptest.NewRecorder()
+	handler.ServeHTTP(identity, httptest.NewRequest(http.MethodGet, "/?count=1", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/?count=1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	compressed := httptest.NewRecorder()
+	handler.ServeHTTP(compressed, req)
+
+	etag := compressed.Header().Get("ETag")
+	if etag == "" || etag == identity.Header().Get("ETag") {
+		t.Fatalf("Got ETag %q for compressed response, want one different from %q", etag, identity.Header().Get("ETag"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?count=1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	if response.Code != http.StatusNotModified {
+		t.Errorf("Got response code %d for matching compressed ETag, want 304", response.Code)
+	}
+	if got := response.Header().Get("ETag"); got != etag {
+		t.Errorf("Got ETag %q for not modified response, want %q", got, etag)
+	}
+
+	// HEAD responses have the same headers as GET ones
+	req = httptest.NewRequest(http.MethodHead, "/?count=1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+
+	for _, name := range []string{"Content-Encoding", "Content-Length", "ETag"} {
+		if got, want := response.Header().Get(name), compressed.Header().Get(name); got != want {
+			t.Errorf("Got %s %q for HEAD request, want %q", name, got, want)
+		}
+	}
+	if response.Body.Len() != 0 {
+		t.Errorf("Got %d bytes of body for HEAD request, want none", response.Body.Len())
+	}
+}
+
 func runRequest(t *testing.T, srv http.Handler, r *http.Request) (content []*provider.ContentItem) {
 	response := httptest.NewRecorder()
 	srv.ServeHTTP(response, r)
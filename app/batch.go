@@ -0,0 +1,146 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
+)
+
+const (
+	batchPath = "/batch"
+
+	// defaultFeedName is the feed served at "/", it's available even if it isn't one of the configured feeds
+	defaultFeedName = "default"
+
+	maxBatchEntries  = 10
+	maxBatchBodySize = 64 * 1024
+)
+
+var (
+	ErrInvalidBatch = errors.New("batch must be a JSON list of 1 to 10 feed requests")
+	ErrUnknownFeed  = errors.New("unknown feed")
+)
+
+// batchEntry requests a page of a feed
+type batchEntry struct {
+	Feed   string `json:"feed"`
+	Count  int    `json:"count"`
+	Offset int    `json:"offset"`
+}
+
+// batchResult is the response to a batch entry, it holds either items or an error
+type batchResult struct {
+	Feed  string          `json:"feed"`
+	Items json.RawMessage `json:"items,omitempty"`
+	Error *errorResponse  `json:"error,omitempty"`
+}
+
+// serveBatch responds to a list of feed requests at once. Items for all of them are fetched from providers together,
+// user details and filters are taken from the request parameters and headers, the same way as for single requests.
+func (a App) serveBatch(w http.ResponseWriter, httpReq *http.Request) {
+	if httpReq.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		handleError(w, httpReq, ErrMethodNotAllowed)
+
+		return
+	}
+
+	req := &request.Request{}
+	if err := req.Parse(httpReq); err != nil {
+		handleError(w, httpReq, err)
+
+		return
+	}
+
+	var entries []batchEntry
+	if err := json.NewDecoder(http.MaxBytesReader(w, httpReq.Body, maxBatchBodySize)).Decode(&entries); err != nil ||
+		len(entries) == 0 || len(entries) > maxBatchEntries {
+		handleError(w, httpReq, ErrInvalidBatch)
+
+		return
+	}
+
+	if a.shedLoad(w, httpReq) {
+		return
+	}
+
+	results := make([]batchResult, len(entries))
+	requests := make([]request.Request, len(entries))
+	mixes := make([]config.ContentMix, len(entries))
+
+	// sum up results needed for all entries, so every provider is called once
+	resPerProvider := make(map[provider.Provider]int)
+	for i, entry := range entries {
+		results[i].Feed = entry.Feed
+
+		mix, variant, err := a.feed(*req, entry.Feed)
+		if err == nil {
+			requests[i], err = req.WithPage(entry.Count, entry.Offset)
+		}
+		if err != nil {
+			_, body := errorStatus(err)
+			results[i].Error = &body
+
+			continue
+		}
+
+		if variant != "" && w.Header().Get(experimentHeaderName) == "" {
+			w.Header().Set(experimentHeaderName, variant)
+			experimentVariants.Add(variant, 1)
+		}
+
+		mixes[i] = mix
+		for providerType, count := range providerCounts(requests[i], mix) {
+			resPerProvider[providerType] += count
+		}
+	}
+
+	resultsPerProvider := a.fetchResults(*req, resPerProvider)
+	seenItems := a.seenItems(*req, resultsPerProvider)
+
+	fields := responseFields(*req)
+
+	// entries take their items from the shared results one after another, in order of the batch
+	var served response.Response
+	for i := range results {
+		if results[i].Error != nil {
+			continue
+		}
+
+		resp, _ := a.prepareResponse(requests[i], mixes[i], resultsPerProvider, seenItems)
+
+		items := &bytes.Buffer{}
+		if err := (response.JSON{}).Serialize(items, resp, fields); err != nil {
+			handleError(w, httpReq, err)
+
+			return
+		}
+
+		results[i].Items = items.Bytes()
+		served = append(served, resp...)
+	}
+
+	handleBatch(w, httpReq, results)
+	a.recordSeenItems(*req, served)
+}
+
+// feed returns the content mix of the named feed, and the experiment variant if the default feed is requested
+func (a App) feed(req request.Request, name string) (config.ContentMix, string, error) {
+	if mix, ok := a.Feeds[name]; ok {
+		return mix, "", nil
+	}
+
+	if name == "" || name == defaultFeedName {
+		mix, variant := a.contentMix(req)
+
+		return mix, variant, nil
+	}
+
+	return nil, "", ErrUnknownFeed
+}
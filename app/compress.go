@@ -0,0 +1,233 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// Compress is a middleware compressing responses with gzip or deflate, depending on Accept-Encoding header.
+// Responses shorter than minSize bytes are sent uncompressed.
+func Compress(next http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := acceptedEncoding(req.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		// ETags of compressed responses have the encoding suffix, the wrapped handler knows only the original ones
+		ifNoneMatch := req.Header.Get("If-None-Match")
+		if ifNoneMatch != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", etagSuffixes.Replace(ifNoneMatch))
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        minSize,
+			head:           req.Method == http.MethodHead,
+			ifNoneMatch:    ifNoneMatch,
+			status:         http.StatusOK,
+		}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, req)
+	})
+}
+
+// acceptedEncoding picks gzip or deflate from Accept-Encoding header, gzip is preferred on equal quality.
+// "*" only applies to encodings not listed explicitly.
+func acceptedEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(params[0]))
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if encoding != "" {
+			qualities[encoding] = quality
+		}
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range []string{encodingGzip, encodingDeflate} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+
+	return best
+}
+
+// etagSuffixes removes encoding suffixes added to ETags of compressed responses
+var etagSuffixes = strings.NewReplacer("-"+encodingGzip+`"`, `"`, "-"+encodingDeflate+`"`, `"`)
+
+// compressWriter buffers the response until it's known whether it's long enough to be compressed
+type compressWriter struct {
+	http.ResponseWriter
+
+	encoding string
+	minSize  int
+	// head responses have no body, whether they'd be compressed is decided by Content-Length header
+	head bool
+	// ifNoneMatch is the header sent by the client, before encoding suffixes were removed
+	ifNoneMatch string
+
+	status      int
+	buffer      bytes.Buffer
+	started     bool
+	passthrough bool
+	compressor  io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.started {
+		return
+	}
+
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if !cw.started {
+		cw.buffer.Write(data)
+		if cw.buffer.Len() < cw.minSize {
+			return len(data), nil
+		}
+
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+
+		return len(data), nil
+	}
+
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(data)
+	}
+
+	return cw.compressor.Write(data)
+}
+
+// Flush sends buffered data right away, it's required by streaming responses
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		_ = cw.start(true)
+	}
+
+	if flusher, ok := cw.compressor.(interface{ Flush() error }); ok && !cw.passthrough {
+		_ = flusher.Flush()
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close writes the rest of the response
+func (cw *compressWriter) Close() error {
+	if !cw.started {
+		if cw.head {
+			length, err := strconv.Atoi(cw.Header().Get("Content-Length"))
+
+			return cw.start(err == nil && length >= cw.minSize)
+		}
+
+		return cw.start(false)
+	}
+
+	if cw.passthrough {
+		return nil
+	}
+
+	return cw.compressor.Close()
+}
+
+// start writes the header and the buffered data, compressing the data if requested and allowed for the response
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+
+	header := cw.Header()
+
+	// a not modified response confirms the ETag the client has, which is the one of the compressed response
+	// if the client sent it with the encoding suffix
+	if etag := cw.suffixedETag(); cw.status == http.StatusNotModified && etag != "" && strings.Contains(cw.ifNoneMatch, etag) {
+		header.Set("ETag", etag)
+	}
+
+	if !compress || header.Get("Content-Encoding") != "" || cw.status < http.StatusOK ||
+		cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+
+		_, err := cw.ResponseWriter.Write(cw.buffer.Bytes())
+
+		return err
+	}
+
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+
+	// the compressed response is a different representation, so it can't share a strong ETag with the original one
+	if etag := cw.suffixedETag(); etag != "" {
+		header.Set("ETag", etag)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	// headers of HEAD responses are the same as of GET ones, there's just nothing to compress
+	if cw.head {
+		cw.passthrough = true
+
+		return nil
+	}
+
+	// deflate content encoding is the zlib format, not raw deflate
+	if cw.encoding == encodingGzip {
+		cw.compressor = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		cw.compressor = zlib.NewWriter(cw.ResponseWriter)
+	}
+
+	_, err := cw.compressor.Write(cw.buffer.Bytes())
+
+	return err
+}
+
+// suffixedETag returns the strong ETag of the response with the encoding suffix, or "" if there's no strong ETag
+func (cw *compressWriter) suffixedETag() string {
+	etag := cw.Header().Get("ETag")
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return ""
+	}
+
+	return etag[:len(etag)-1] + "-" + cw.encoding + `"`
+}
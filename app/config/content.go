@@ -1,12 +1,23 @@
 package config
 
-import "github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+import (
+	"errors"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrEmptyContentMix   = errors.New("content mix is empty")
+	ErrUnknownProvider   = errors.New("unknown provider")
+	ErrSameFallback      = errors.New("fallback is the same as the provider")
+	ErrEmptyProviderType = errors.New("provider type is empty")
+)
 
 type ContentMix []ContentConfig
 
 type ContentConfig struct {
-	Type     provider.Provider
-	Fallback *provider.Provider
+	Type     provider.Provider  `json:"type"`
+	Fallback *provider.Provider `json:"fallback,omitempty"`
 }
 
 var (
@@ -29,3 +40,56 @@ var (
 
 	DefaultContentMix = ContentMix{config1, config1, config2, config3, config4, config1, config1, config2}
 )
+
+// Validate checks the mix isn't empty and refers only to known providers.
+// Providers aren't checked against if known is empty.
+func (m ContentMix) Validate(known []provider.Provider) error {
+	if len(m) == 0 {
+		return ErrEmptyContentMix
+	}
+
+	isKnown := func(providerType provider.Provider) bool {
+		if len(known) == 0 {
+			return true
+		}
+
+		for i := range known {
+			if known[i] == providerType {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, contentConfig := range m {
+		if contentConfig.Type == "" {
+			return ErrEmptyProviderType
+		}
+		if !isKnown(contentConfig.Type) {
+			return ErrUnknownProvider
+		}
+
+		if contentConfig.Fallback == nil {
+			continue
+		}
+		if *contentConfig.Fallback == contentConfig.Type {
+			return ErrSameFallback
+		}
+		if !isKnown(*contentConfig.Fallback) {
+			return ErrUnknownProvider
+		}
+	}
+
+	return nil
+}
+
+// Sequence returns configs used for count positions starting from offset
+func (m ContentMix) Sequence(count, offset int) []ContentConfig {
+	res := make([]ContentConfig, 0, count)
+	for i := offset; i < offset+count; i++ {
+		res = append(res, m[i%len(m)])
+	}
+
+	return res
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io/ioutil"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrEmptyExperimentName = errors.New("experiment name is empty")
+	ErrEmptyVariantName    = errors.New("variant name is empty")
+	ErrDuplicateVariant    = errors.New("variant name is used more than once")
+	ErrNoVariantWeight     = errors.New("experiment has no variants with weight")
+)
+
+// Variant is one of the content mixes compared in an experiment
+type Variant struct {
+	Name string `json:"name"`
+	// Weight is the relative share of users assigned to the variant
+	Weight uint32     `json:"weight"`
+	Mix    ContentMix `json:"mix"`
+}
+
+// Experiment splits users between content mix variants
+type Experiment struct {
+	Name     string    `json:"name"`
+	Variants []Variant `json:"variants"`
+}
+
+// Validate checks the experiment and all its variants are named, some variant has weight,
+// and mixes of all variants are valid
+func (e Experiment) Validate(known []provider.Provider) error {
+	if e.Name == "" {
+		return ErrEmptyExperimentName
+	}
+
+	var total uint32
+	names := make(map[string]bool, len(e.Variants))
+	for i := range e.Variants {
+		if e.Variants[i].Name == "" {
+			return ErrEmptyVariantName
+		}
+		if names[e.Variants[i].Name] {
+			return ErrDuplicateVariant
+		}
+		names[e.Variants[i].Name] = true
+
+		if err := e.Variants[i].Mix.Validate(known); err != nil {
+			return err
+		}
+
+		total += e.Variants[i].Weight
+	}
+
+	if total == 0 {
+		return ErrNoVariantWeight
+	}
+
+	return nil
+}
+
+// LoadExperiment reads an experiment from a JSON file
+func LoadExperiment(path string, known []provider.Provider) (*Experiment, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	experiment := &Experiment{}
+	if err := json.Unmarshal(data, experiment); err != nil {
+		return nil, err
+	}
+
+	if err := experiment.Validate(known); err != nil {
+		return nil, err
+	}
+
+	return experiment, nil
+}
+
+// Assign deterministically picks a variant for the user identifier, returns nil if there are no variants to pick from
+func (e Experiment) Assign(userID string) *Variant {
+	var total uint32
+	for i := range e.Variants {
+		total += e.Variants[i].Weight
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(e.Name + ":" + userID))
+
+	bucket := hash.Sum32() % total
+	for i := range e.Variants {
+		if bucket < e.Variants[i].Weight {
+			return &e.Variants[i]
+		}
+
+		bucket -= e.Variants[i].Weight
+	}
+
+	return nil
+}
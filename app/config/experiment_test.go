@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestExperiment_Validate(t *testing.T) {
+	known := []provider.Provider{provider.Provider1, provider.Provider2}
+	mix := ContentMix{ContentConfig{Type: provider.Provider1}}
+
+	testCases := []struct {
+		name          string
+		experiment    Experiment
+		expectedError error
+	}{
+		{
+			name:       "Valid",
+			experiment: Experiment{Name: "test", Variants: []Variant{{Name: "a", Weight: 1, Mix: mix}, {Name: "b", Mix: mix}}},
+		},
+		{
+			name:          "Empty name",
+			experiment:    Experiment{Variants: []Variant{{Name: "a", Weight: 1, Mix: mix}}},
+			expectedError: ErrEmptyExperimentName,
+		},
+		{
+			name:          "Empty variant name",
+			experiment:    Experiment{Name: "test", Variants: []Variant{{Weight: 1, Mix: mix}}},
+			expectedError: ErrEmptyVariantName,
+		},
+		{
+			name:          "Duplicate variant name",
+			experiment:    Experiment{Name: "test", Variants: []Variant{{Name: "a", Weight: 1, Mix: mix}, {Name: "a", Weight: 1, Mix: mix}}},
+			expectedError: ErrDuplicateVariant,
+		},
+		{
+			name:          "Empty variant mix",
+			experiment:    Experiment{Name: "test", Variants: []Variant{{Name: "a", Weight: 1}}},
+			expectedError: ErrEmptyContentMix,
+		},
+		{
+			name:          "Unknown provider",
+			experiment:    Experiment{Name: "test", Variants: []Variant{{Name: "a", Weight: 1, Mix: ContentMix{ContentConfig{Type: provider.Provider3}}}}},
+			expectedError: ErrUnknownProvider,
+		},
+		{
+			name:          "No weight",
+			experiment:    Experiment{Name: "test", Variants: []Variant{{Name: "a", Mix: mix}}},
+			expectedError: ErrNoVariantWeight,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.experiment.Validate(known); err != test.expectedError {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrEmptyFeedName = errors.New("feed name is empty")
+)
+
+// Feeds are content mixes by feed name
+type Feeds map[string]ContentMix
+
+// Validate checks every feed has a name and a valid content mix
+func (f Feeds) Validate(known []provider.Provider) error {
+	for name, mix := range f {
+		if name == "" {
+			return ErrEmptyFeedName
+		}
+
+		if err := mix.Validate(known); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFeeds reads feeds from a JSON file, which maps feed names to content mixes
+func LoadFeeds(path string, known []provider.Provider) (Feeds, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var feeds Feeds
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, err
+	}
+
+	if err := feeds.Validate(known); err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
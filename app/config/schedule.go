@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrInvalidWindow = errors.New("window hours must be from 0 to 23 for start and 0 to 24 for end, and differ")
+	ErrInvalidDay    = errors.New("window weekday must be from 0 (Sunday) to 6 (Saturday)")
+)
+
+// Window is a recurring weekly time window from StartHour (inclusive) till EndHour (exclusive).
+// A window with EndHour before StartHour spans midnight, empty Weekdays means every day.
+type Window struct {
+	Weekdays  []time.Weekday `json:"weekdays,omitempty"`
+	StartHour int            `json:"start_hour"`
+	EndHour   int            `json:"end_hour"`
+}
+
+// Validate checks window hours and weekdays are in range
+func (w Window) Validate() error {
+	if w.StartHour < 0 || w.StartHour > 23 || w.EndHour < 0 || w.EndHour > 24 || w.StartHour == w.EndHour {
+		return ErrInvalidWindow
+	}
+
+	for i := range w.Weekdays {
+		if w.Weekdays[i] < time.Sunday || w.Weekdays[i] > time.Saturday {
+			return ErrInvalidDay
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether the window contains t, in t's location
+func (w Window) Contains(t time.Time) bool {
+	hour := t.Hour()
+
+	if w.StartHour <= w.EndHour {
+		return hour >= w.StartHour && hour < w.EndHour && w.onWeekday(t.Weekday())
+	}
+
+	// the window spans midnight, so hours after midnight belong to the window started the day before
+	if hour >= w.StartHour {
+		return w.onWeekday(t.Weekday())
+	}
+	if hour < w.EndHour {
+		return w.onWeekday(t.AddDate(0, 0, -1).Weekday())
+	}
+
+	return false
+}
+
+func (w Window) onWeekday(weekday time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+
+	for i := range w.Weekdays {
+		if w.Weekdays[i] == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScheduledMix is a content mix used within a time window
+type ScheduledMix struct {
+	Name   string     `json:"name"`
+	Window Window     `json:"window"`
+	Mix    ContentMix `json:"mix"`
+}
+
+// Schedule picks a content mix depending on the time of day and day of week
+type Schedule struct {
+	// Location is the timezone windows are defined in, UTC if nil
+	Location *time.Location
+	// Mixes are checked in order, the first one with a window containing the time wins
+	Mixes []ScheduledMix
+}
+
+// Validate checks windows and mixes of all scheduled mixes
+func (s Schedule) Validate(known []provider.Provider) error {
+	for i := range s.Mixes {
+		if err := s.Mixes[i].Window.Validate(); err != nil {
+			return err
+		}
+
+		if err := s.Mixes[i].Mix.Validate(known); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadSchedule reads a schedule from a JSON file with the name of the timezone windows are defined in
+// and the list of scheduled mixes, e.g. {"timezone": "Europe/London", "mixes": [...]}
+func LoadSchedule(path string, known []provider.Provider) (*Schedule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := struct {
+		Timezone string         `json:"timezone"`
+		Mixes    []ScheduledMix `json:"mixes"`
+	}{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	// empty timezone name means UTC
+	location, err := time.LoadLocation(file.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &Schedule{Location: location, Mixes: file.Mixes}
+	if err := schedule.Validate(known); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// Active returns the scheduled mix for the given time, or nil if none of the windows contains it
+func (s Schedule) Active(t time.Time) *ScheduledMix {
+	location := s.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	t = t.In(location)
+	for i := range s.Mixes {
+		if s.Mixes[i].Window.Contains(t) {
+			return &s.Mixes[i]
+		}
+	}
+
+	return nil
+}
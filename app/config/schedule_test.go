@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("timezone database is not available: %v", err)
+	}
+
+	schedule := Schedule{
+		Location: london,
+		Mixes: []ScheduledMix{
+			{
+				Name:   "morning",
+				Window: Window{Weekdays: []time.Weekday{time.Monday, time.Tuesday}, StartHour: 6, EndHour: 10},
+				Mix:    ContentMix{ContentConfig{Type: provider.Provider1}},
+			},
+			{
+				Name:   "night",
+				Window: Window{Weekdays: []time.Weekday{time.Friday}, StartHour: 22, EndHour: 2},
+				Mix:    ContentMix{ContentConfig{Type: provider.Provider2}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		at           string
+		expectedName string
+	}{
+		{name: "Morning in window", at: "2020-09-21T07:30:00+01:00", expectedName: "morning"},
+		{name: "Morning in window, UTC time", at: "2020-09-21T05:30:00Z", expectedName: "morning"},
+		{name: "Morning outside of window hours", at: "2020-09-21T10:00:00+01:00"},
+		{name: "Morning on another weekday", at: "2020-09-23T07:30:00+01:00"},
+		{name: "Night before midnight", at: "2020-09-25T23:00:00+01:00", expectedName: "night"},
+		{name: "Night after midnight", at: "2020-09-26T01:00:00+01:00", expectedName: "night"},
+		{name: "Night after midnight of another weekday", at: "2020-09-25T01:00:00+01:00"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, test.at)
+			if err != nil {
+				t.Fatalf("couldn't parse time: %v", err)
+			}
+
+			name := ""
+			if active := schedule.Active(at); active != nil {
+				name = active.Name
+			}
+
+			if name != test.expectedName {
+				t.Fatalf("active mix check failed: expected to get '%v', but got '%v'", test.expectedName, name)
+			}
+		})
+	}
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	known := []provider.Provider{provider.Provider1}
+	mix := ContentMix{ContentConfig{Type: provider.Provider1}}
+
+	testCases := []struct {
+		name          string
+		scheduled     ScheduledMix
+		expectedError error
+	}{
+		{name: "Valid", scheduled: ScheduledMix{Window: Window{StartHour: 0, EndHour: 24}, Mix: mix}},
+		{name: "Spans midnight", scheduled: ScheduledMix{Window: Window{StartHour: 22, EndHour: 2}, Mix: mix}},
+		{name: "Start hour too big", scheduled: ScheduledMix{Window: Window{StartHour: 24, EndHour: 2}, Mix: mix}, expectedError: ErrInvalidWindow},
+		{name: "End hour too big", scheduled: ScheduledMix{Window: Window{StartHour: 2, EndHour: 25}, Mix: mix}, expectedError: ErrInvalidWindow},
+		{name: "Negative hour", scheduled: ScheduledMix{Window: Window{StartHour: -1, EndHour: 2}, Mix: mix}, expectedError: ErrInvalidWindow},
+		{name: "Empty window", scheduled: ScheduledMix{Window: Window{StartHour: 2, EndHour: 2}, Mix: mix}, expectedError: ErrInvalidWindow},
+		{name: "Invalid weekday", scheduled: ScheduledMix{Window: Window{Weekdays: []time.Weekday{7}, StartHour: 2, EndHour: 3}, Mix: mix}, expectedError: ErrInvalidDay},
+		{name: "Empty mix", scheduled: ScheduledMix{Window: Window{StartHour: 2, EndHour: 3}}, expectedError: ErrEmptyContentMix},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			schedule := Schedule{Mixes: []ScheduledMix{test.scheduled}}
+			if err := schedule.Validate(known); err != test.expectedError {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown content mix version")
+)
+
+// Version is a content mix as it was applied at some point
+type Version struct {
+	ID        int        `json:"id"`
+	Mix       ContentMix `json:"mix"`
+	AppliedBy string     `json:"applied_by"`
+	AppliedAt time.Time  `json:"applied_at"`
+	// RollbackOf is the ID of the version restored by this one
+	RollbackOf int `json:"rollback_of,omitempty"`
+}
+
+// Store keeps the active content mix and the history of all applied mixes, it's safe for concurrent use
+type Store struct {
+	// Providers are used to validate applied mixes
+	Providers []provider.Provider
+
+	mu       sync.RWMutex
+	versions []Version
+}
+
+func NewStore(mix ContentMix, providers []provider.Provider) *Store {
+	return &Store{
+		Providers: providers,
+		versions: []Version{{
+			ID:        1,
+			Mix:       mix,
+			AppliedBy: "initial",
+			AppliedAt: time.Now(),
+		}},
+	}
+}
+
+// Current returns the active content mix
+func (s *Store) Current() ContentMix {
+	return s.CurrentVersion().Mix
+}
+
+// CurrentVersion returns the active version
+func (s *Store) CurrentVersion() Version {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.versions[len(s.versions)-1]
+}
+
+// History returns all applied versions, oldest first
+func (s *Store) History() []Version {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Version(nil), s.versions...)
+}
+
+// Apply validates the mix and makes it active
+func (s *Store) Apply(mix ContentMix, user string) (Version, error) {
+	if err := mix.Validate(s.Providers); err != nil {
+		return Version{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apply(mix, user, 0), nil
+}
+
+// Rollback makes a previously applied version active again, recording it as a new version
+func (s *Store) Rollback(id int, user string) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > len(s.versions) {
+		return Version{}, ErrUnknownVersion
+	}
+
+	return s.apply(s.versions[id-1].Mix, user, id), nil
+}
+
+func (s *Store) apply(mix ContentMix, user string, rollbackOf int) Version {
+	version := Version{
+		ID:         len(s.versions) + 1,
+		Mix:        append(ContentMix(nil), mix...),
+		AppliedBy:  user,
+		AppliedAt:  time.Now(),
+		RollbackOf: rollbackOf,
+	}
+	s.versions = append(s.versions, version)
+
+	return version
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
+)
+
+const (
+	debugTokenHeaderName = "X-Debug-Token"
+
+	reasonTimeout    = "timeout"
+	reasonError      = "error"
+	reasonExhausted  = "exhausted"
+	reasonBudget     = "budget"
+	reasonExpired    = "expired"
+	reasonOverloaded = "overloaded"
+)
+
+var (
+	ErrDebugForbidden = errors.New("debug mode is not authorised")
+)
+
+// slot explains how a single position of the response was filled
+type slot struct {
+	Position     int                `json:"position"`
+	ConfigIndex  int                `json:"config_index"`
+	Provider     provider.Provider  `json:"provider"`
+	Fallback     *provider.Provider `json:"fallback,omitempty"`
+	FallbackUsed bool               `json:"fallback_used"`
+	// Reason explains why the primary provider didn't fill the slot
+	Reason string `json:"reason,omitempty"`
+	Filled bool   `json:"filled"`
+}
+
+type providerDebug struct {
+	Requested  int    `json:"requested"`
+	Received   int    `json:"received"`
+	Expired    int    `json:"expired"`
+	Filtered   int    `json:"filtered"`
+	Suppressed int    `json:"suppressed"`
+	Latency    string `json:"latency"`
+	Error      string `json:"error,omitempty"`
+}
+
+type debugResponse struct {
+	Items     response.Response                   `json:"items"`
+	Slots     []slot                              `json:"slots"`
+	Providers map[provider.Provider]providerDebug `json:"providers"`
+}
+
+func newDebugResponse(resp response.Response, slots []slot, resultsPerProvider map[provider.Provider]*providerResult) debugResponse {
+	for i := range slots {
+		slots[i].Filled = i < len(resp)
+	}
+
+	providers := make(map[provider.Provider]providerDebug, len(resultsPerProvider))
+	for providerType, result := range resultsPerProvider {
+		info := providerDebug{
+			Requested:  result.requested,
+			Received:   result.received,
+			Expired:    result.expired,
+			Filtered:   result.filtered,
+			Suppressed: result.suppressed,
+			Latency:    result.latency.String(),
+		}
+		if result.err != nil {
+			info.Error = result.err.Error()
+		}
+
+		providers[providerType] = info
+	}
+
+	return debugResponse{
+		Items:     resp,
+		Slots:     slots,
+		Providers: providers,
+	}
+}
+
+// failureReason explains why a provider had no content left for a slot
+func (r *providerResult) failureReason() string {
+	switch r.err {
+	case nil:
+		if r.expired > 0 {
+			return reasonExpired
+		}
+
+		return reasonExhausted
+	case context.DeadlineExceeded:
+		return reasonTimeout
+	case provider.ErrBudgetExhausted:
+		return reasonBudget
+	case ErrProviderOverloaded:
+		return reasonOverloaded
+	default:
+		return reasonError
+	}
+}
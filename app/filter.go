@@ -0,0 +1,93 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
+)
+
+const (
+	// filteredOverFetchFactor is how many more items are requested from providers
+	// when the request filters content, to make up for filtered out items
+	filteredOverFetchFactor = 2
+)
+
+// dropExpired drops items which expired before the given time, items without expiry are kept.
+// It returns the remaining items and the number of dropped ones
+func dropExpired(items []*provider.ContentItem, now time.Time) ([]*provider.ContentItem, int) {
+	res := items[:0:0]
+	for _, item := range items {
+		if item != nil && !item.Expiry.IsZero() && item.Expiry.Before(now) {
+			continue
+		}
+
+		res = append(res, item)
+	}
+
+	return res, len(items) - len(res)
+}
+
+// filterItems normalises items, and drops invalid items, blocked items and items the request doesn't want
+func (a App) filterItems(req request.Request, providerType provider.Provider, items []*provider.ContentItem) []*provider.ContentItem {
+	if a.Normalizer == nil && a.Moderator == nil && !isFiltered(req) {
+		return items
+	}
+
+	res := items[:0:0]
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+
+		if a.Normalizer != nil {
+			// clients may share items between calls, so a copy is normalised
+			normalized := *item
+			if err := a.Normalizer.Normalize(&normalized); err != nil {
+				normalizationRejected.Add(string(providerType), 1)
+
+				continue
+			}
+
+			item = &normalized
+		}
+
+		if a.Moderator != nil && a.Moderator.Blocked(item) {
+			moderationBlocked.Add(string(providerType), 1)
+
+			continue
+		}
+
+		if matchesCategories(req, item) {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+func isFiltered(req request.Request) bool {
+	return len(req.Include) > 0 || len(req.Exclude) > 0
+}
+
+// matchesCategories checks the item has any of the included categories and none of the excluded ones
+func matchesCategories(req request.Request, item *provider.ContentItem) bool {
+	has := func(categories []string) bool {
+		for _, itemCategory := range item.Categories {
+			for _, category := range categories {
+				if strings.EqualFold(itemCategory, category) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	if len(req.Include) > 0 && !has(req.Include) {
+		return false
+	}
+
+	return !has(req.Exclude)
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	ErrOverloaded         = errors.New("too many provider calls in flight")
+	ErrProviderOverloaded = errors.New("too many calls waiting for the provider")
+)
+
+// Limiter caps the number of in-flight provider calls globally and per provider.
+// A slot is held until the provider call returns, even if the caller has already given up on it,
+// so hanging providers can't spawn an unbounded number of goroutines.
+// Calls waiting for a slot of a single provider fail fast once its queue is full, so a hanging provider
+// doesn't get requests shed, only calls waiting for a global slot count towards overload.
+type Limiter struct {
+	global      chan struct{}
+	perProvider int
+	maxQueue    int64
+	queued      int64
+
+	mu        sync.Mutex
+	providers map[provider.Provider]*providerLimit
+}
+
+// providerLimit holds slots of calls in flight to a provider, and the number of calls waiting for one
+type providerLimit struct {
+	slots  chan struct{}
+	queued int64
+}
+
+// NewLimiter creates a limiter allowing up to global calls in flight, up to perProvider calls
+// to each provider, and allowing up to maxQueue calls to wait for a global slot or a slot of each provider.
+// Requests are shed once more calls are waiting for a global slot.
+func NewLimiter(global, perProvider, maxQueue int) *Limiter {
+	return &Limiter{
+		global:      make(chan struct{}, global),
+		perProvider: perProvider,
+		maxQueue:    int64(maxQueue),
+		providers:   make(map[provider.Provider]*providerLimit),
+	}
+}
+
+// Overloaded reports whether new requests should be shed
+func (l *Limiter) Overloaded() bool {
+	return atomic.LoadInt64(&l.queued) > l.maxQueue
+}
+
+// RetryAfter estimates how long a shed client should wait before retrying,
+// growing with the number of calls waiting for a slot.
+func (l *Limiter) RetryAfter() time.Duration {
+	backlog := atomic.LoadInt64(&l.queued) / int64(cap(l.global)+1)
+
+	return time.Second + time.Duration(backlog)*loadContentTimeout
+}
+
+func (l *Limiter) acquire(ctx context.Context, providerType provider.Provider) (func(), error) {
+	limit := l.providerLimit(providerType)
+
+	if err := l.acquireProviderSlot(ctx, limit); err != nil {
+		return nil, err
+	}
+
+	atomic.AddInt64(&l.queued, 1)
+	defer atomic.AddInt64(&l.queued, -1)
+
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		<-limit.slots
+
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-l.global
+		<-limit.slots
+	}, nil
+}
+
+// acquireProviderSlot waits for a slot of the provider, failing right away with ErrProviderOverloaded
+// if maxQueue calls are already waiting
+func (l *Limiter) acquireProviderSlot(ctx context.Context, limit *providerLimit) error {
+	select {
+	case limit.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if atomic.AddInt64(&limit.queued, 1) > l.maxQueue {
+		atomic.AddInt64(&limit.queued, -1)
+
+		return ErrProviderOverloaded
+	}
+	defer atomic.AddInt64(&limit.queued, -1)
+
+	select {
+	case limit.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) providerLimit(providerType provider.Provider) *providerLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.providers[providerType]
+	if !ok {
+		limit = &providerLimit{slots: make(chan struct{}, l.perProvider)}
+		l.providers[providerType] = limit
+	}
+
+	return limit
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"expvar"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+var (
+	budgetExhausted       = expvar.NewMap("provider_budget_exhausted")
+	experimentVariants    = expvar.NewMap("experiment_variants")
+	moderationBlocked     = expvar.NewMap("moderation_blocked")
+	normalizationRejected = expvar.NewMap("normalization_rejected")
+)
+
+// BudgetStats returns current budget usage per provider, it's meant to be published via expvar
+func (a App) BudgetStats() interface{} {
+	stats := make(map[string]provider.BudgetStats, len(a.Budgets))
+	for providerType, budget := range a.Budgets {
+		stats[string(providerType)] = budget.Stats()
+	}
+
+	return stats
+}
@@ -0,0 +1,252 @@
+// Package moderation drops content items matching a blocklist.
+package moderation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+// Blocklist describes content which must not be served
+type Blocklist struct {
+	// Domains block links to the domain and all of its subdomains
+	Domains []string `json:"domains"`
+	IDs     []string `json:"ids"`
+	// Keywords block items with the keyword in the title or the summary, case insensitive
+	Keywords []string `json:"keywords"`
+}
+
+// Moderator checks items against a blocklist, which can be replaced at runtime.
+// If the blocklist is loaded from a file, changes to the file are picked up by Reload,
+// and blocklists set at runtime are written back to the file.
+type Moderator struct {
+	path string
+
+	// fileMu serialises updates of the blocklist, so the file and the blocklist in memory stay in sync
+	fileMu sync.Mutex
+
+	mu        sync.RWMutex
+	blocklist Blocklist
+	domains   map[string]bool
+	ids       map[string]bool
+	keywords  []string
+	modTime   time.Time
+}
+
+func NewModerator(blocklist Blocklist) *Moderator {
+	m := &Moderator{}
+	m.set(blocklist)
+
+	return m
+}
+
+// LoadModerator creates a moderator with the blocklist from a JSON file
+func LoadModerator(path string) (*Moderator, error) {
+	m := &Moderator{path: path}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Blocklist returns the current blocklist
+func (m *Moderator) Blocklist() Blocklist {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.blocklist
+}
+
+// SetBlocklist replaces the blocklist, saving it to the file if the moderator was loaded from one
+func (m *Moderator) SetBlocklist(blocklist Blocklist) error {
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	if m.path == "" {
+		m.set(blocklist)
+
+		return nil
+	}
+
+	data, err := json.MarshalIndent(blocklist, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(m.path, data); err != nil {
+		return err
+	}
+
+	m.set(blocklist)
+
+	// the file is already in sync, no need to reload it
+	if info, err := os.Stat(m.path); err == nil {
+		m.mu.Lock()
+		m.modTime = info.ModTime()
+		m.mu.Unlock()
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		return err
+	}
+
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+
+		return err
+	}
+
+	return nil
+}
+
+// Reload reads the blocklist file again if it was modified since the last read
+func (m *Moderator) Reload() error {
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	modified := !info.ModTime().Equal(m.modTime)
+	m.mu.RUnlock()
+
+	if !modified {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(m.path)
+	if err != nil {
+		return err
+	}
+
+	blocklist := Blocklist{}
+	if err := json.Unmarshal(data, &blocklist); err != nil {
+		return err
+	}
+
+	m.set(blocklist)
+
+	m.mu.Lock()
+	m.modTime = info.ModTime()
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the blocklist file every interval until stop is closed
+func (m *Moderator) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Reload(); err != nil {
+				log.Printf("couldn't reload blocklist: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Blocked reports whether the item matches the blocklist
+func (m *Moderator) Blocked(item *provider.ContentItem) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.ids[item.ID] {
+		return true
+	}
+
+	if link, err := url.Parse(item.Link); err == nil && link.Hostname() != "" {
+		host := strings.ToLower(link.Hostname())
+		for {
+			if m.domains[host] {
+				return true
+			}
+
+			dot := strings.Index(host, ".")
+			if dot < 0 {
+				break
+			}
+			host = host[dot+1:]
+		}
+	}
+
+	title := strings.ToLower(item.Title)
+	summary := strings.ToLower(item.Summary)
+	for _, keyword := range m.keywords {
+		if strings.Contains(title, keyword) || strings.Contains(summary, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Moderator) set(blocklist Blocklist) {
+	domains := make(map[string]bool, len(blocklist.Domains))
+	for _, domain := range blocklist.Domains {
+		domains[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+
+	ids := make(map[string]bool, len(blocklist.IDs))
+	for _, id := range blocklist.IDs {
+		ids[id] = true
+	}
+
+	keywords := make([]string, 0, len(blocklist.Keywords))
+	for _, keyword := range blocklist.Keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocklist = blocklist
+	m.domains = domains
+	m.ids = ids
+	m.keywords = keywords
+}
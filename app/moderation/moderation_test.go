@@ -0,0 +1,132 @@
+package moderation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestModerator_Blocked(t *testing.T) {
+	moderator := NewModerator(Blocklist{
+		Domains:  []string{"blocked.com"},
+		IDs:      []string{"blocked-id"},
+		Keywords: []string{"Spoiler"},
+	})
+
+	testCases := []struct {
+		name     string
+		item     provider.ContentItem
+		expected bool
+	}{
+		{name: "Allowed item", item: provider.ContentItem{ID: "1", Title: "title", Link: "https://allowed.com/1"}},
+		{name: "Blocked ID", item: provider.ContentItem{ID: "blocked-id"}, expected: true},
+		{name: "Blocked domain", item: provider.ContentItem{ID: "1", Link: "https://BLOCKED.com/1"}, expected: true},
+		{name: "Blocked subdomain", item: provider.ContentItem{ID: "1", Link: "https://news.blocked.com/1"}, expected: true},
+		{name: "Similar domain", item: provider.ContentItem{ID: "1", Link: "https://notblocked.com/1"}},
+		{name: "Blocked keyword in title", item: provider.ContentItem{ID: "1", Title: "Big spoiler"}, expected: true},
+		{name: "Blocked keyword in summary", item: provider.ContentItem{ID: "1", Summary: "SPOILERS ahead"}, expected: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			if blocked := moderator.Blocked(&test.item); blocked != test.expected {
+				t.Fatalf("blocked check failed: expected to get '%v', but got '%v'", test.expected, blocked)
+			}
+		})
+	}
+}
+
+func TestModerator_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "moderation")
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "blocklist.json")
+	if err := ioutil.WriteFile(path, []byte(`{"ids": ["1"]}`), 0644); err != nil {
+		t.Fatalf("couldn't write blocklist: %v", err)
+	}
+
+	moderator, err := LoadModerator(path)
+	if err != nil {
+		t.Fatalf("couldn't load blocklist: %v", err)
+	}
+
+	if !moderator.Blocked(&provider.ContentItem{ID: "1"}) {
+		t.Fatalf("item 1 is expected to be blocked")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"ids": ["2"]}`), 0644); err != nil {
+		t.Fatalf("couldn't write blocklist: %v", err)
+	}
+	// make sure modification time changes even on file systems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("couldn't update blocklist modification time: %v", err)
+	}
+
+	if err := moderator.Reload(); err != nil {
+		t.Fatalf("couldn't reload blocklist: %v", err)
+	}
+
+	if moderator.Blocked(&provider.ContentItem{ID: "1"}) || !moderator.Blocked(&provider.ContentItem{ID: "2"}) {
+		t.Fatalf("only item 2 is expected to be blocked after reload")
+	}
+}
+
+func TestModerator_SetBlocklist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "moderation")
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "blocklist.json")
+	if err := ioutil.WriteFile(path, []byte(`{}`), 0644); err != nil {
+		t.Fatalf("couldn't write blocklist: %v", err)
+	}
+
+	moderator, err := LoadModerator(path)
+	if err != nil {
+		t.Fatalf("couldn't load blocklist: %v", err)
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		id := strconv.Itoa(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := moderator.SetBlocklist(Blocklist{IDs: []string{id}}); err != nil {
+				t.Errorf("couldn't set blocklist: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := LoadModerator(path)
+	if err != nil {
+		t.Fatalf("couldn't load saved blocklist: %v", err)
+	}
+
+	if current := moderator.Blocklist(); len(current.IDs) != 1 || saved.Blocklist().IDs[0] != current.IDs[0] {
+		t.Fatalf("Got blocklist %+v saved, want %+v", saved.Blocklist(), current)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("couldn't read temp dir: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Got %d files in the blocklist directory, want only the blocklist", len(files))
+	}
+}
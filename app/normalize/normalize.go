@@ -0,0 +1,133 @@
+// Package normalize cleans up content items received from providers.
+package normalize
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+const (
+	ellipsis = "…"
+)
+
+var (
+	ErrInvalidLink = errors.New("invalid link")
+
+	tagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Normalizer strips HTML from texts, truncates summaries and cleans up links
+type Normalizer struct {
+	// MaxSummaryLength is the max number of characters in a summary, summaries aren't truncated if 0
+	MaxSummaryLength int
+}
+
+// Normalize cleans the item up in place. It returns ErrInvalidLink if the item has a link that isn't
+// an absolute http(s) URL, such items should be rejected.
+func (n Normalizer) Normalize(item *provider.ContentItem) error {
+	link, err := normalizeLink(item.Link)
+	if err != nil {
+		return err
+	}
+
+	item.Link = link
+	item.Title = normalizeText(item.Title)
+	item.Summary = truncate(normalizeText(item.Summary), n.MaxSummaryLength)
+	item.Author = normalizeText(item.Author)
+
+	// broken images are dropped, an item is still fine without them
+	images := item.Images[:0:0]
+	for _, image := range item.Images {
+		if image.URL, err = normalizeLink(image.URL); err == nil && image.URL != "" {
+			images = append(images, image)
+		}
+	}
+	item.Images = images
+
+	return nil
+}
+
+// normalizeText strips HTML tags, decodes entities and collapses whitespace
+func normalizeText(text string) string {
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	// entities may have encoded markup
+	text = tagPattern.ReplaceAllString(text, " ")
+
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, text)
+
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// truncate shortens text to max characters, cutting at a word boundary
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if max <= 0 || len(runes) <= max {
+		return text
+	}
+
+	cut := max - len([]rune(ellipsis))
+	if cut <= 0 {
+		return string(runes[:max])
+	}
+
+	// step back to the last space, unless the first word alone is too long
+	end := cut
+	for end > 0 && !unicode.IsSpace(runes[end]) {
+		end--
+	}
+	if end == 0 {
+		end = cut
+	}
+
+	return strings.TrimRightFunc(string(runes[:end]), unicode.IsSpace) + ellipsis
+}
+
+// normalizeLink validates the link and strips tracking parameters from it, empty links are kept as they are
+func normalizeLink(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrInvalidLink
+	}
+
+	// parameters are removed from the raw query, so the order and encoding of the others are kept
+	// for signed and order sensitive links
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+
+		kept := params[:0:0]
+		for _, param := range params {
+			key := strings.SplitN(param, "=", 2)[0]
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+
+			if !strings.HasPrefix(strings.ToLower(key), "utm_") {
+				kept = append(kept, param)
+			}
+		}
+
+		if len(kept) < len(params) {
+			u.RawQuery = strings.Join(kept, "&")
+		}
+	}
+
+	return u.String(), nil
+}
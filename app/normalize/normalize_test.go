@@ -0,0 +1,81 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	normalizer := Normalizer{MaxSummaryLength: 20}
+
+	testCases := []struct {
+		name           string
+		item           provider.ContentItem
+		expectedResult provider.ContentItem
+		expectedError  error
+	}{
+		{
+			name:           "Clean item",
+			item:           provider.ContentItem{Title: "Title", Summary: "Summary", Link: "https://1.com/1"},
+			expectedResult: provider.ContentItem{Title: "Title", Summary: "Summary", Link: "https://1.com/1"},
+		},
+		{
+			name:           "HTML and entities",
+			item:           provider.ContentItem{Title: "<b>Fish &amp; Chips</b>", Summary: "&lt;script&gt;alert(1)&lt;/script&gt;Tasty"},
+			expectedResult: provider.ContentItem{Title: "Fish & Chips", Summary: "alert(1) Tasty"},
+		},
+		{
+			name:           "Whitespace",
+			item:           provider.ContentItem{Title: "  Breaking\n\n news\t\x00 "},
+			expectedResult: provider.ContentItem{Title: "Breaking news"},
+		},
+		{
+			name:           "Long summary",
+			item:           provider.ContentItem{Summary: "The quick brown fox jumps over the lazy dog"},
+			expectedResult: provider.ContentItem{Summary: "The quick brown fox…"},
+		},
+		{
+			name:           "Tracking parameters",
+			item:           provider.ContentItem{Link: "https://1.com/1?id=5&utm_source=feed&UTM_medium=app"},
+			expectedResult: provider.ContentItem{Link: "https://1.com/1?id=5"},
+		},
+		{
+			name:           "Tracking parameters between others",
+			item:           provider.ContentItem{Link: "https://1.com/1?b=2&utm_source=feed&a=1&flag&sig=a%2Fb"},
+			expectedResult: provider.ContentItem{Link: "https://1.com/1?b=2&a=1&flag&sig=a%2Fb"},
+		},
+		{
+			name:           "No tracking parameters",
+			item:           provider.ContentItem{Link: "https://1.com/1?b=2&a=1&flag&sig=a%2Fb"},
+			expectedResult: provider.ContentItem{Link: "https://1.com/1?b=2&a=1&flag&sig=a%2Fb"},
+		},
+		{
+			name:          "Invalid link",
+			item:          provider.ContentItem{Link: "://malformed"},
+			expectedError: ErrInvalidLink,
+		},
+		{
+			name:          "Unsupported link scheme",
+			item:          provider.ContentItem{Link: "javascript:alert(1)"},
+			expectedError: ErrInvalidLink,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			item := test.item
+			err := normalizer.Normalize(&item)
+
+			if err != test.expectedError {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+
+			if test.expectedError == nil && (item.Title != test.expectedResult.Title ||
+				item.Summary != test.expectedResult.Summary ||
+				item.Link != test.expectedResult.Link) {
+				t.Fatalf("item check failed: expected to get '%+v', but got '%+v'", test.expectedResult, item)
+			}
+		})
+	}
+}
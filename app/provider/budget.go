@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBudgetExhausted = errors.New("provider budget exhausted")
+)
+
+// Budget limits the number of calls made to a provider per minute and per day.
+// A zero limit means the corresponding window is unlimited.
+type Budget struct {
+	PerMinute int
+	PerDay    int
+
+	mu          sync.Mutex
+	minuteStart time.Time
+	minuteCalls int
+	dayStart    time.Time
+	dayCalls    int
+	exhausted   uint64
+}
+
+// BudgetStats is a snapshot of a budget state
+type BudgetStats struct {
+	PerMinute       int    `json:"per_minute"`
+	PerDay          int    `json:"per_day"`
+	UsedMinute      int    `json:"used_minute"`
+	UsedDay         int    `json:"used_day"`
+	ExhaustedCalls  uint64 `json:"exhausted_calls"`
+	RemainingMinute int    `json:"remaining_minute"`
+	RemainingDay    int    `json:"remaining_day"`
+}
+
+func NewBudget(perMinute, perDay int) *Budget {
+	return &Budget{
+		PerMinute: perMinute,
+		PerDay:    perDay,
+	}
+}
+
+// Allow reserves one call from the budget. It returns false if either window is exhausted.
+func (b *Budget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.resetWindows()
+
+	if (b.PerMinute > 0 && b.minuteCalls >= b.PerMinute) || (b.PerDay > 0 && b.dayCalls >= b.PerDay) {
+		b.exhausted++
+
+		return false
+	}
+
+	b.minuteCalls++
+	b.dayCalls++
+
+	return true
+}
+
+// BudgetedClient fails calls with ErrBudgetExhausted once the budget is used up, so only calls actually made
+// to the wrapped client are counted. Wrap it with a CoalescingClient, so a shared call is counted once.
+type BudgetedClient struct {
+	Client Client
+	Budget *Budget
+}
+
+func NewBudgetedClient(client Client, budget *Budget) *BudgetedClient {
+	return &BudgetedClient{
+		Client: client,
+		Budget: budget,
+	}
+}
+
+// GetContent returns content items from the wrapped client if there's budget left for the call.
+func (cp *BudgetedClient) GetContent(userIP string, count int) ([]*ContentItem, error) {
+	return cp.GetPersonalisedContent(ContentRequest{UserIP: userIP, Count: count})
+}
+
+// GetPersonalisedContent is the same as GetContent, passing all request details to the wrapped client.
+func (cp *BudgetedClient) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	if !cp.Budget.Allow() {
+		return nil, ErrBudgetExhausted
+	}
+
+	return GetContent(cp.Client, req)
+}
+
+// Stats returns the current budget usage
+func (b *Budget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.resetWindows()
+
+	stats := BudgetStats{
+		PerMinute:       b.PerMinute,
+		PerDay:          b.PerDay,
+		UsedMinute:      b.minuteCalls,
+		UsedDay:         b.dayCalls,
+		ExhaustedCalls:  b.exhausted,
+		RemainingMinute: -1,
+		RemainingDay:    -1,
+	}
+	if b.PerMinute > 0 {
+		stats.RemainingMinute = b.PerMinute - b.minuteCalls
+	}
+	if b.PerDay > 0 {
+		stats.RemainingDay = b.PerDay - b.dayCalls
+	}
+
+	return stats
+}
+
+func (b *Budget) resetWindows() {
+	now := time.Now().UTC()
+
+	if minute := now.Truncate(time.Minute); !minute.Equal(b.minuteStart) {
+		b.minuteStart = minute
+		b.minuteCalls = 0
+	}
+
+	if day := now.Truncate(24 * time.Hour); !day.Equal(b.dayStart) {
+		b.dayStart = day
+		b.dayCalls = 0
+	}
+}
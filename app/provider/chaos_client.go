@@ -0,0 +1,212 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	LatencyFixed       = "fixed"
+	LatencyUniform     = "uniform"
+	LatencyNormal      = "normal"
+	LatencyExponential = "exponential"
+
+	defaultHangDuration = time.Minute
+)
+
+var (
+	ErrChaos              = errors.New("injected provider error")
+	ErrInvalidChaosConfig = errors.New("invalid chaos config")
+)
+
+// Duration is a time.Duration represented as a string like "150ms" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// ChaosConfig describes faults injected by ChaosClient. Rates are probabilities in range [0, 1].
+type ChaosConfig struct {
+	// Latency is one of "fixed" (always Mean), "uniform" (between Min and Max),
+	// "normal" (Mean and StdDev) or "exponential" (Mean), empty means no extra latency
+	Latency       string   `json:"latency,omitempty"`
+	LatencyMin    Duration `json:"latency_min,omitempty"`
+	LatencyMax    Duration `json:"latency_max,omitempty"`
+	LatencyMean   Duration `json:"latency_mean,omitempty"`
+	LatencyStdDev Duration `json:"latency_stddev,omitempty"`
+
+	ErrorRate     float64 `json:"error_rate,omitempty"`
+	PartialRate   float64 `json:"partial_rate,omitempty"`
+	MalformedRate float64 `json:"malformed_rate,omitempty"`
+	HangRate      float64 `json:"hang_rate,omitempty"`
+
+	// HangDuration is how long a hanging call blocks, one minute by default
+	HangDuration Duration `json:"hang_duration,omitempty"`
+}
+
+// Validate checks rates and latency settings are consistent
+func (c ChaosConfig) Validate() error {
+	for _, rate := range []float64{c.ErrorRate, c.PartialRate, c.MalformedRate, c.HangRate} {
+		if rate < 0 || rate > 1 {
+			return ErrInvalidChaosConfig
+		}
+	}
+
+	if c.LatencyMin < 0 || c.LatencyMax < c.LatencyMin || c.LatencyMean < 0 || c.LatencyStdDev < 0 || c.HangDuration < 0 {
+		return ErrInvalidChaosConfig
+	}
+
+	switch c.Latency {
+	case "", LatencyFixed, LatencyUniform, LatencyNormal, LatencyExponential:
+		return nil
+	default:
+		return ErrInvalidChaosConfig
+	}
+}
+
+// ChaosClient wraps a client and injects latency, errors, partial responses, malformed items and hangs.
+// Its configuration can be changed at runtime.
+type ChaosClient struct {
+	Client Client
+
+	mu     sync.RWMutex
+	config ChaosConfig
+	rand   *rand.Rand
+}
+
+func NewChaosClient(client Client) *ChaosClient {
+	return &ChaosClient{
+		Client: client,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Config returns the current chaos configuration
+func (cp *ChaosClient) Config() ChaosConfig {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+
+	return cp.config
+}
+
+// SetConfig validates and applies a new chaos configuration
+func (cp *ChaosClient) SetConfig(config ChaosConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	cp.mu.Lock()
+	cp.config = config
+	cp.mu.Unlock()
+
+	return nil
+}
+
+// GetContent returns content items from the wrapped client with configured faults injected.
+func (cp *ChaosClient) GetContent(userIP string, count int) ([]*ContentItem, error) {
+	return cp.GetPersonalisedContent(ContentRequest{UserIP: userIP, Count: count})
+}
+
+// GetPersonalisedContent is the same as GetContent, passing all request details to the wrapped client.
+func (cp *ChaosClient) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	config := cp.Config()
+
+	if cp.happens(config.HangRate) {
+		hang := time.Duration(config.HangDuration)
+		if hang == 0 {
+			hang = defaultHangDuration
+		}
+		time.Sleep(hang)
+	}
+
+	if latency := cp.latency(config); latency > 0 {
+		time.Sleep(latency)
+	}
+
+	if cp.happens(config.ErrorRate) {
+		return nil, ErrChaos
+	}
+
+	res, err := GetContent(cp.Client, req)
+	if err != nil {
+		return res, err
+	}
+
+	// don't modify the slice owned by the wrapped client
+	res = append([]*ContentItem(nil), res...)
+
+	if len(res) > 0 && cp.happens(config.PartialRate) {
+		res = res[:cp.intn(len(res))]
+	}
+
+	for i := range res {
+		if res[i] != nil && cp.happens(config.MalformedRate) {
+			res[i] = &ContentItem{
+				ID:     "",
+				Title:  "<b>\x00malformed",
+				Source: res[i].Source,
+				Link:   "://malformed",
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (cp *ChaosClient) latency(config ChaosConfig) time.Duration {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	var latency float64
+	switch config.Latency {
+	case LatencyFixed:
+		latency = float64(config.LatencyMean)
+	case LatencyUniform:
+		latency = float64(config.LatencyMin) + cp.rand.Float64()*float64(config.LatencyMax-config.LatencyMin)
+	case LatencyNormal:
+		latency = float64(config.LatencyMean) + cp.rand.NormFloat64()*float64(config.LatencyStdDev)
+	case LatencyExponential:
+		latency = cp.rand.ExpFloat64() * float64(config.LatencyMean)
+	}
+
+	return time.Duration(math.Max(latency, 0))
+}
+
+func (cp *ChaosClient) happens(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return cp.rand.Float64() < rate
+}
+
+func (cp *ChaosClient) intn(n int) int {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return cp.rand.Intn(n)
+}
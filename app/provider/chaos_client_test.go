@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChaosClient_GetContent(t *testing.T) {
+	testCases := []struct {
+		name              string
+		config            ChaosConfig
+		expectedError     error
+		expectedMinItems  int
+		expectedMaxItems  int
+		expectedMalformed bool
+		expectedMinDelay  time.Duration
+	}{
+		{name: "No faults", config: ChaosConfig{}, expectedMinItems: 5, expectedMaxItems: 5},
+		{name: "Errors", config: ChaosConfig{ErrorRate: 1}, expectedError: ErrChaos},
+		{name: "Partial responses", config: ChaosConfig{PartialRate: 1}, expectedMinItems: 0, expectedMaxItems: 4},
+		{name: "Malformed items", config: ChaosConfig{MalformedRate: 1}, expectedMinItems: 5, expectedMaxItems: 5, expectedMalformed: true},
+		{
+			name:             "Hangs",
+			config:           ChaosConfig{HangRate: 1, HangDuration: Duration(time.Millisecond * 50)},
+			expectedMinItems: 5,
+			expectedMaxItems: 5,
+			expectedMinDelay: time.Millisecond * 50,
+		},
+		{
+			name:             "Fixed latency",
+			config:           ChaosConfig{Latency: LatencyFixed, LatencyMean: Duration(time.Millisecond * 50)},
+			expectedMinItems: 5,
+			expectedMaxItems: 5,
+			expectedMinDelay: time.Millisecond * 50,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewChaosClient(&ContentProviderMock{Source: Provider1})
+			if err := client.SetConfig(test.config); err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+
+			started := time.Now()
+			res, err := client.GetContent("127.0.0.1", 5)
+
+			if err != test.expectedError {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+			if elapsed := time.Since(started); elapsed < test.expectedMinDelay {
+				t.Errorf("delay check failed: expected at least '%v', but got '%v'", test.expectedMinDelay, elapsed)
+			}
+			if len(res) < test.expectedMinItems || len(res) > test.expectedMaxItems {
+				t.Fatalf("items check failed: expected to get %d to %d items, but got %d", test.expectedMinItems, test.expectedMaxItems, len(res))
+			}
+
+			for i := range res {
+				if malformed := res[i].ID == ""; malformed != test.expectedMalformed {
+					t.Errorf("item %d: malformed check failed: expected to get '%v', but got '%v'", i, test.expectedMalformed, malformed)
+				}
+			}
+		})
+	}
+}
+
+func TestChaosClient_Latency(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      ChaosConfig
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{name: "None", config: ChaosConfig{}, expectedMin: 0, expectedMax: 0},
+		{
+			name:        "Fixed",
+			config:      ChaosConfig{Latency: LatencyFixed, LatencyMean: Duration(time.Second)},
+			expectedMin: time.Second,
+			expectedMax: time.Second,
+		},
+		{
+			name:        "Uniform",
+			config:      ChaosConfig{Latency: LatencyUniform, LatencyMin: Duration(time.Second), LatencyMax: Duration(time.Second * 2)},
+			expectedMin: time.Second,
+			expectedMax: time.Second * 2,
+		},
+		{
+			name:        "Normal without deviation",
+			config:      ChaosConfig{Latency: LatencyNormal, LatencyMean: Duration(time.Second)},
+			expectedMin: time.Second,
+			expectedMax: time.Second,
+		},
+		{
+			name:        "Exponential",
+			config:      ChaosConfig{Latency: LatencyExponential, LatencyMean: Duration(time.Second)},
+			expectedMin: 0,
+			expectedMax: time.Hour,
+		},
+	}
+
+	client := NewChaosClient(&ContentProviderMock{Source: Provider1})
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if latency := client.latency(test.config); latency < test.expectedMin || latency > test.expectedMax {
+					t.Fatalf("latency check failed: expected to get '%v' to '%v', but got '%v'", test.expectedMin, test.expectedMax, latency)
+				}
+			}
+		})
+	}
+}
+
+func TestChaosConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        ChaosConfig
+		expectedError error
+	}{
+		{name: "Empty", config: ChaosConfig{}},
+		{name: "Valid", config: ChaosConfig{Latency: LatencyUniform, LatencyMax: Duration(time.Second), ErrorRate: 0.5, HangRate: 1}},
+		{name: "Rate over 1", config: ChaosConfig{ErrorRate: 1.5}, expectedError: ErrInvalidChaosConfig},
+		{name: "Negative rate", config: ChaosConfig{MalformedRate: -0.1}, expectedError: ErrInvalidChaosConfig},
+		{
+			name:          "Maximum under minimum",
+			config:        ChaosConfig{Latency: LatencyUniform, LatencyMin: Duration(time.Second), LatencyMax: Duration(time.Millisecond)},
+			expectedError: ErrInvalidChaosConfig,
+		},
+		{name: "Negative hang", config: ChaosConfig{HangDuration: Duration(-time.Second)}, expectedError: ErrInvalidChaosConfig},
+		{name: "Unknown latency", config: ChaosConfig{Latency: "random"}, expectedError: ErrInvalidChaosConfig},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.Validate(); err != test.expectedError {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+		})
+	}
+}
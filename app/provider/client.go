@@ -12,9 +12,48 @@ type ContentItem struct {
 	Summary string    `json:"summary"`
 	Link    string    `json:"link"`
 	Expiry  time.Time `json:"expiry"`
+
+	Images      []Image    `json:"images,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+}
+
+// Image is a picture illustrating a content item, dimensions are in pixels
+type Image struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
 }
 
 // Client represents a provider's client or SDK
 type Client interface {
 	GetContent(userIP string, count int) ([]*ContentItem, error)
 }
+
+// ContentRequest carries the details a provider may use to personalise content
+type ContentRequest struct {
+	UserIP     string `json:"user_ip"`
+	Count      int    `json:"count"`
+	UserID     string `json:"user_id,omitempty"`
+	Locale     string `json:"locale,omitempty"`
+	Device     string `json:"device,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+}
+
+// PersonalisedClient represents a provider's client able to personalise content
+type PersonalisedClient interface {
+	Client
+	GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error)
+}
+
+// GetContent fetches content with all request details if the client supports personalisation,
+// and with user IP and count only otherwise
+func GetContent(client Client, req ContentRequest) ([]*ContentItem, error) {
+	if personalisedClient, ok := client.(PersonalisedClient); ok {
+		return personalisedClient.GetPersonalisedContent(req)
+	}
+
+	return client.GetContent(req.UserIP, req.Count)
+}
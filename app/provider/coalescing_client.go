@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CoalescingClient shares a single upstream call between concurrent identical fetches.
+// Fetches are identical when they ask for the same number of items for the same user segment.
+type CoalescingClient struct {
+	Client Client
+
+	// Segment maps an anonymous request, one without user ID and app version, to the user segment the content
+	// is fetched for, content of a segment is shared by its users. Requests with user ID or app version
+	// are passed through with all details. If nil, every combination of user IP and personalisation details
+	// is a segment on its own
+	Segment func(req ContentRequest) string
+
+	mu       sync.Mutex
+	inFlight map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	wg  sync.WaitGroup
+	res []*ContentItem
+	err error
+}
+
+func NewCoalescingClient(client Client, segment func(req ContentRequest) string) *CoalescingClient {
+	return &CoalescingClient{
+		Client:  client,
+		Segment: segment,
+	}
+}
+
+// SegmentByNetwork puts users of the same /24 IPv4 or /48 IPv6 network with the same locale and device in a segment
+func SegmentByNetwork(req ContentRequest) string {
+	network := req.UserIP
+	if ip := net.ParseIP(req.UserIP); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			network = ip4.Mask(net.CIDRMask(24, 32)).String()
+		} else {
+			network = ip.Mask(net.CIDRMask(48, 128)).String()
+		}
+	}
+
+	return strings.Join([]string{network, req.Locale, req.Device}, "/")
+}
+
+// GetContent returns content items from the wrapped client, joining an identical call in flight if there's one.
+func (cp *CoalescingClient) GetContent(userIP string, count int) ([]*ContentItem, error) {
+	return cp.GetPersonalisedContent(ContentRequest{UserIP: userIP, Count: count})
+}
+
+// GetPersonalisedContent is the same as GetContent, passing all request details to the wrapped client.
+func (cp *CoalescingClient) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	key := strings.Join([]string{req.UserIP, strconv.Itoa(req.Count), req.UserID, req.Locale, req.Device, req.AppVersion}, "/")
+	if cp.Segment != nil && req.UserID == "" && req.AppVersion == "" {
+		key = cp.Segment(req) + "/" + strconv.Itoa(req.Count)
+	}
+
+	cp.mu.Lock()
+	if cp.inFlight == nil {
+		cp.inFlight = make(map[string]*coalescedCall)
+	}
+
+	if call, ok := cp.inFlight[key]; ok {
+		cp.mu.Unlock()
+		call.wg.Wait()
+
+		return copyItems(call.res), call.err
+	}
+
+	call := &coalescedCall{}
+	call.wg.Add(1)
+	cp.inFlight[key] = call
+	cp.mu.Unlock()
+
+	call.res, call.err = GetContent(cp.Client, req)
+	call.wg.Done()
+
+	cp.mu.Lock()
+	delete(cp.inFlight, key)
+	cp.mu.Unlock()
+
+	return copyItems(call.res), call.err
+}
+
+// copyItems makes sure every caller gets its own items, so they can be modified independently
+func copyItems(items []*ContentItem) []*ContentItem {
+	if items == nil {
+		return nil
+	}
+
+	res := make([]*ContentItem, len(items))
+	for i := range items {
+		if items[i] == nil {
+			continue
+		}
+
+		item := *items[i]
+		item.Images = append([]Image(nil), item.Images...)
+		item.Categories = append([]string(nil), item.Categories...)
+		res[i] = &item
+	}
+
+	return res
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingClient struct {
+	client *ContentProviderMock
+	calls  int32
+
+	mu       sync.Mutex
+	requests []ContentRequest
+}
+
+func (cp *countingClient) GetContent(userIP string, count int) ([]*ContentItem, error) {
+	atomic.AddInt32(&cp.calls, 1)
+
+	return cp.client.GetContent(userIP, count)
+}
+
+func (cp *countingClient) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	atomic.AddInt32(&cp.calls, 1)
+
+	cp.mu.Lock()
+	cp.requests = append(cp.requests, req)
+	cp.mu.Unlock()
+
+	return cp.client.GetPersonalisedContent(req)
+}
+
+func TestCoalescingClient_GetContent(t *testing.T) {
+	upstream := &countingClient{client: &ContentProviderMock{Source: Provider1}}
+	upstream.client.SetDelay(time.Millisecond * 100)
+
+	client := NewCoalescingClient(upstream, func(ContentRequest) string { return "segment" })
+
+	wg := &sync.WaitGroup{}
+	results := make([][]*ContentItem, 5)
+	for i := range results {
+		i := i
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			results[i], _ = client.GetContent("10.0.0."+string(rune('1'+i)), 3)
+		}()
+	}
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&upstream.calls); calls != 1 {
+		t.Fatalf("Got %d upstream calls, want 1", calls)
+	}
+
+	for i := range results {
+		if len(results[i]) != 3 {
+			t.Fatalf("Got %d items back, want 3", len(results[i]))
+		}
+		if results[i][0].ID != results[0][0].ID {
+			t.Errorf("Got ID %v instead of ID %v", results[i][0].ID, results[0][0].ID)
+		}
+		if i > 0 && results[i][0] == results[0][0] {
+			t.Errorf("Items are shared between callers")
+		}
+	}
+
+	if _, _ = client.GetContent("10.0.0.1", 3); atomic.LoadInt32(&upstream.calls) != 2 {
+		t.Fatalf("Got %d upstream calls, want 2", upstream.calls)
+	}
+}
+
+func TestCoalescingClient_SegmentByNetwork(t *testing.T) {
+	upstream := &ContentProviderMock{Source: Provider1}
+	upstream.SetDelay(time.Millisecond * 100)
+	counting := &countingClient{client: upstream}
+
+	client := NewCoalescingClient(counting, SegmentByNetwork)
+
+	requests := []ContentRequest{
+		{UserIP: "10.0.0.1", Count: 3, Locale: "en-GB"},
+		{UserIP: "10.0.0.2", Count: 3, Locale: "en-GB"},
+		{UserIP: "10.0.1.1", Count: 3, Locale: "en-GB"},
+		{UserIP: "10.0.0.3", Count: 3, UserID: "alice", Locale: "en-GB", AppVersion: "1.0"},
+		{UserIP: "10.0.0.4", Count: 3, Locale: "en-GB", AppVersion: "2.0"},
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := range requests {
+		req := requests[i]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _ = client.GetPersonalisedContent(req)
+		}()
+	}
+	wg.Wait()
+
+	// the first two anonymous users are in the same network, the third one is in another segment,
+	// users with user ID or app version are never coalesced with others
+	if calls := atomic.LoadInt32(&counting.calls); calls != 4 {
+		t.Fatalf("Got %d upstream calls, want 4", calls)
+	}
+
+	details := map[string]bool{}
+	for _, req := range counting.requests {
+		if req.UserID != "" || req.AppVersion != "" {
+			details[req.UserID+"/"+req.AppVersion] = true
+		}
+	}
+	if !details["alice/1.0"] || !details["/2.0"] {
+		t.Errorf("Got upstream requests %+v, want user ID and app version passed through", counting.requests)
+	}
+}
+
+func TestCoalescingClient_Budget(t *testing.T) {
+	upstream := &ContentProviderMock{Source: Provider1}
+	upstream.SetDelay(time.Millisecond * 100)
+
+	budget := NewBudget(0, 2)
+	client := NewCoalescingClient(NewBudgetedClient(upstream, budget), func(ContentRequest) string { return "segment" })
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, _ = client.GetContent("10.0.0.1", 3)
+		}()
+	}
+	wg.Wait()
+
+	// concurrent calls are shared, so the budget is used once
+	if used := budget.Stats().UsedDay; used != 1 {
+		t.Fatalf("Got %d budget calls used, want 1", used)
+	}
+
+	if _, err := client.GetContent("10.0.0.1", 3); err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	if _, err := client.GetContent("10.0.0.1", 3); err != ErrBudgetExhausted {
+		t.Fatalf("Got error '%v', want '%v'", err, ErrBudgetExhausted)
+	}
+}
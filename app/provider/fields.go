@@ -0,0 +1,24 @@
+package provider
+
+var (
+	// ItemFields are names of content item fields which can be selected, as they appear in JSON
+	ItemFields = []string{
+		"id", "title", "source", "summary", "link", "expiry",
+		"images", "author", "published_at", "language", "categories",
+	}
+
+	// LegacyItemFields are content item fields served before images, authors and other details were added,
+	// the other fields are optional and omitted when empty. Capacity is limited, so appending never overwrites ItemFields
+	LegacyItemFields = ItemFields[:6:6]
+)
+
+// IsItemField reports whether the name is one of the content item fields
+func IsItemField(name string) bool {
+	for i := range ItemFields {
+		if ItemFields[i] == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,13 @@
+package provider
+
+import (
+	"testing"
+)
+
+func TestLegacyItemFields_Append(t *testing.T) {
+	fields := append(LegacyItemFields, "extra")
+
+	if ItemFields[len(LegacyItemFields)] != "images" || len(fields) != len(LegacyItemFields)+1 {
+		t.Fatalf("Got item fields %v after appending to legacy fields, want them unchanged", ItemFields)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -13,6 +14,9 @@ type ContentProviderMock struct {
 	delay    time.Duration
 	err      error
 	response []*ContentItem
+
+	mu          sync.Mutex
+	lastRequest ContentRequest
 }
 
 func (cp *ContentProviderMock) SetDelay(delay time.Duration) {
@@ -27,6 +31,21 @@ func (cp *ContentProviderMock) SetResponse(response []*ContentItem) {
 	cp.response = response
 }
 
+func (cp *ContentProviderMock) LastRequest() ContentRequest {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return cp.lastRequest
+}
+
+func (cp *ContentProviderMock) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	cp.mu.Lock()
+	cp.lastRequest = req
+	cp.mu.Unlock()
+
+	return cp.GetContent(req.UserIP, req.Count)
+}
+
 func (cp *ContentProviderMock) GetContent(_ string, count int) ([]*ContentItem, error) {
 	if cp.delay > 0 {
 		time.Sleep(cp.delay)
@@ -42,6 +61,7 @@ func (cp *ContentProviderMock) GetContent(_ string, count int) ([]*ContentItem,
 
 	resp := make([]*ContentItem, count)
 	for i := range resp {
+		publishedAt := time.Now().Add(-time.Hour)
 		resp[i] = &ContentItem{
 			ID:      strconv.Itoa(rand.Int()),
 			Title:   fmt.Sprintf("Item #%d", i),
@@ -49,6 +69,12 @@ func (cp *ContentProviderMock) GetContent(_ string, count int) ([]*ContentItem,
 			Summary: fmt.Sprintf("Item summary #%d", i),
 			Link:    fmt.Sprintf("https://%s.com/%d", cp.Source, i),
 			Expiry:  time.Now(),
+
+			Images:      []Image{{URL: fmt.Sprintf("https://%s.com/%d.jpg", cp.Source, i), Width: 640, Height: 360}},
+			Author:      fmt.Sprintf("Author #%d", i),
+			PublishedAt: &publishedAt,
+			Language:    "en",
+			Categories:  []string{"news"},
 		}
 	}
 
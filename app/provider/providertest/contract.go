@@ -0,0 +1,169 @@
+// Package providertest provides utilities for testing provider.Client implementations.
+package providertest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+const (
+	defaultContractTimeout     = time.Second * 2
+	defaultContractConcurrency = 10
+)
+
+// ContractOptions tunes RunContractTests
+type ContractOptions struct {
+	// Timeout is the longest a single GetContent call may take, 2 seconds by default
+	Timeout time.Duration
+	// Concurrency is the number of simultaneous calls made by the concurrency check, 10 by default
+	Concurrency int
+}
+
+// RunContractTests checks a Client implementation behaves the way the app relies on:
+// it returns at most count items, populates required ContentItem fields, returns in time,
+// is safe for concurrent use and handles count=0. Every call has to return within the timeout,
+// including calls made concurrently. Run it with -race to catch data races.
+func RunContractTests(t *testing.T, client provider.Client, opts ContractOptions) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultContractTimeout
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = defaultContractConcurrency
+	}
+
+	t.Run("Returns at most count items", func(t *testing.T) {
+		for _, count := range []int{1, 5, 100} {
+			res, err := getContentWithin(t, client, contentRequest(count), opts.Timeout)
+			if err != nil {
+				t.Fatalf("count %d: unexpected error '%v'", count, err)
+			}
+
+			if len(res) > count {
+				t.Errorf("count %d: got %d items back", count, len(res))
+			}
+		}
+	})
+
+	t.Run("Populates required fields", func(t *testing.T) {
+		res, err := getContentWithin(t, client, contentRequest(5), opts.Timeout)
+		if err != nil {
+			t.Fatalf("unexpected error '%v'", err)
+		}
+
+		for i, item := range res {
+			if item == nil {
+				t.Errorf("item %d: is nil", i)
+
+				continue
+			}
+
+			if item.ID == "" {
+				t.Errorf("item %d: ID is empty", i)
+			}
+			if item.Title == "" {
+				t.Errorf("item %d: title is empty", i)
+			}
+			if item.Source == "" {
+				t.Errorf("item %d: source is empty", i)
+			}
+			if item.Expiry.IsZero() {
+				t.Errorf("item %d: expiry is not set", i)
+			}
+		}
+	})
+
+	t.Run("Returns within timeout", func(t *testing.T) {
+		if _, err := getContentWithin(t, client, contentRequest(5), opts.Timeout); err != nil {
+			t.Fatalf("unexpected error '%v'", err)
+		}
+
+		personalised := provider.ContentRequest{
+			UserIP:     "127.0.0.1",
+			Count:      5,
+			UserID:     "contract",
+			Locale:     "en-GB",
+			Device:     "phone",
+			AppVersion: "1.0.0",
+		}
+		if _, err := getContentWithin(t, client, personalised, opts.Timeout); err != nil {
+			t.Fatalf("personalised request: unexpected error '%v'", err)
+		}
+	})
+
+	t.Run("Safe for concurrent use", func(t *testing.T) {
+		wg := &sync.WaitGroup{}
+		errs := make([]error, opts.Concurrency)
+
+		for i := 0; i < opts.Concurrency; i++ {
+			i := i
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, errs[i] = provider.GetContent(client, contentRequest(5))
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		// concurrent calls must not queue up behind each other beyond the timeout
+		select {
+		case <-done:
+		case <-time.After(opts.Timeout):
+			t.Fatalf("%d concurrent calls didn't return within %v", opts.Concurrency, opts.Timeout)
+		}
+
+		for i := range errs {
+			if errs[i] != nil {
+				t.Errorf("call %d: unexpected error '%v'", i, errs[i])
+			}
+		}
+	})
+
+	t.Run("Handles zero count", func(t *testing.T) {
+		res, err := getContentWithin(t, client, contentRequest(0), opts.Timeout)
+		if err != nil {
+			t.Fatalf("unexpected error '%v'", err)
+		}
+
+		if len(res) != 0 {
+			t.Errorf("got %d items back, want 0", len(res))
+		}
+	})
+}
+
+func contentRequest(count int) provider.ContentRequest {
+	return provider.ContentRequest{UserIP: "127.0.0.1", Count: count}
+}
+
+func getContentWithin(t *testing.T, client provider.Client, req provider.ContentRequest, timeout time.Duration) ([]*provider.ContentItem, error) {
+	t.Helper()
+
+	type result struct {
+		res []*provider.ContentItem
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		res, err := provider.GetContent(client, req)
+		done <- result{res: res, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-time.After(timeout):
+		t.Fatalf("count %d: no response within %v", req.Count, timeout)
+
+		return nil, nil
+	}
+}
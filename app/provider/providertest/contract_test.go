@@ -0,0 +1,20 @@
+package providertest
+
+import (
+	"testing"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestSampleContentProvider_Contract(t *testing.T) {
+	RunContractTests(t, &provider.SampleContentProvider{Source: provider.Provider1}, ContractOptions{})
+}
+
+func TestContentProviderMock_Contract(t *testing.T) {
+	RunContractTests(t, &provider.ContentProviderMock{Source: provider.Provider1}, ContractOptions{})
+}
+
+func TestCoalescingClient_Contract(t *testing.T) {
+	client := provider.NewCoalescingClient(&provider.SampleContentProvider{Source: provider.Provider1}, provider.SegmentByNetwork)
+	RunContractTests(t, client, ContractOptions{})
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrReplayExhausted = errors.New("no more recorded responses to replay")
+)
+
+// Recording is a single captured GetContent call
+type Recording struct {
+	ContentRequest
+
+	Time     time.Time      `json:"time"`
+	Source   Provider       `json:"source"`
+	Duration Duration       `json:"duration"`
+	Items    []*ContentItem `json:"items"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Recorder writes captured provider traffic as JSON lines, it's safe to share between clients
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Wrap returns a client recording every call made to the given client
+func (r *Recorder) Wrap(source Provider, client Client) *RecordingClient {
+	return &RecordingClient{
+		Client:   client,
+		Source:   source,
+		Recorder: r,
+	}
+}
+
+func (r *Recorder) write(recording Recording) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.encoder.Encode(recording)
+}
+
+// RecordingClient passes calls through to the wrapped client and records requests and responses
+type RecordingClient struct {
+	Client   Client
+	Source   Provider
+	Recorder *Recorder
+}
+
+// GetContent returns content items from the wrapped client. Failing to record a call doesn't fail the call.
+func (cp *RecordingClient) GetContent(userIP string, count int) ([]*ContentItem, error) {
+	return cp.GetPersonalisedContent(ContentRequest{UserIP: userIP, Count: count})
+}
+
+// GetPersonalisedContent is the same as GetContent, passing all request details to the wrapped client.
+func (cp *RecordingClient) GetPersonalisedContent(req ContentRequest) ([]*ContentItem, error) {
+	started := time.Now()
+	res, err := GetContent(cp.Client, req)
+
+	recording := Recording{
+		ContentRequest: req,
+		Time:           started,
+		Source:         cp.Source,
+		Duration:       Duration(time.Since(started)),
+		Items:          res,
+	}
+	if err != nil {
+		recording.Error = err.Error()
+	}
+
+	_ = cp.Recorder.write(recording)
+
+	return res, err
+}
+
+// ReplayClient serves recorded calls of one provider back in the order they were captured
+type ReplayClient struct {
+	Source Provider
+	// Delay makes replayed calls take as long as the recorded ones
+	Delay bool
+
+	mu         sync.Mutex
+	recordings []Recording
+	next       int
+}
+
+// NewReplayClient reads recordings of the given provider from JSON lines
+func NewReplayClient(r io.Reader, source Provider) (*ReplayClient, error) {
+	client := &ReplayClient{Source: source}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		recording := Recording{}
+		if err := json.Unmarshal(scanner.Bytes(), &recording); err != nil {
+			return nil, err
+		}
+
+		if recording.Source == source {
+			client.recordings = append(client.recordings, recording)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// LoadReplayClient reads recordings of the given provider from a JSON lines file
+func LoadReplayClient(path string, source Provider) (*ReplayClient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewReplayClient(file, source)
+}
+
+// GetContent returns the next recorded response, truncated to count items.
+func (cp *ReplayClient) GetContent(_ string, count int) ([]*ContentItem, error) {
+	cp.mu.Lock()
+	if cp.next >= len(cp.recordings) {
+		cp.mu.Unlock()
+
+		return nil, ErrReplayExhausted
+	}
+
+	recording := cp.recordings[cp.next]
+	cp.next++
+	cp.mu.Unlock()
+
+	if cp.Delay {
+		time.Sleep(time.Duration(recording.Duration))
+	}
+
+	if recording.Error != "" {
+		return nil, errors.New(recording.Error)
+	}
+
+	res := copyItems(recording.Items)
+	if len(res) > count {
+		res = res[:count]
+	}
+
+	// items expire as long after the replay as they did after the recording
+	for i := range res {
+		if res[i] != nil && !res[i].Expiry.IsZero() && !recording.Time.IsZero() {
+			res[i].Expiry = time.Now().Add(res[i].Expiry.Sub(recording.Time))
+		}
+	}
+
+	return res, nil
+}
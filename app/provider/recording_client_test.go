@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRecordingClient_Replay(t *testing.T) {
+	upstream := &ContentProviderMock{Source: Provider1}
+
+	buffer := &bytes.Buffer{}
+	recorder := NewRecorder(buffer)
+	client := recorder.Wrap(Provider1, upstream)
+
+	recorded, err := client.GetContent("192.0.2.1", 3)
+	if err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+
+	upstream.SetError(errors.New("expected error"))
+	if _, err := client.GetContent("192.0.2.1", 3); err == nil {
+		t.Fatalf("expected to get an error")
+	}
+
+	replay, err := NewReplayClient(buffer, Provider1)
+	if err != nil {
+		t.Fatalf("couldn't read recordings: %v", err)
+	}
+
+	replayed, err := replay.GetContent("", 3)
+	if err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	if len(replayed) != len(recorded) {
+		t.Fatalf("Got %d items back, want %d", len(replayed), len(recorded))
+	}
+	for i := range replayed {
+		if replayed[i].ID != recorded[i].ID {
+			t.Errorf("Got ID %v instead of ID %v", replayed[i].ID, recorded[i].ID)
+		}
+	}
+
+	if _, err := replay.GetContent("", 3); err == nil || err.Error() != "expected error" {
+		t.Errorf("Got error '%v', want 'expected error'", err)
+	}
+
+	if _, err := replay.GetContent("", 3); err != ErrReplayExhausted {
+		t.Errorf("Got error '%v', want '%v'", err, ErrReplayExhausted)
+	}
+}
@@ -15,6 +15,7 @@ type SampleContentProvider struct {
 func (cp *SampleContentProvider) GetContent(userIP string, count int) ([]*ContentItem, error) {
 	resp := make([]*ContentItem, count)
 	for i, _ := range resp {
+		publishedAt := time.Now().Add(-time.Hour)
 		resp[i] = &ContentItem{
 			ID:      strconv.Itoa(rand.Int()),
 			Title:   "title",
@@ -22,6 +23,12 @@ func (cp *SampleContentProvider) GetContent(userIP string, count int) ([]*Conten
 			Summary: "",
 			Link:    "",
 			Expiry:  time.Now(),
+
+			Images:      []Image{{URL: "https://example.com/image.jpg", Width: 640, Height: 360}},
+			Author:      "author",
+			PublishedAt: &publishedAt,
+			Language:    "en",
+			Categories:  []string{"news"},
 		}
 
 	}
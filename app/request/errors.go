@@ -0,0 +1,44 @@
+package request
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a request parameter with an invalid value
+type ValidationError struct {
+	Param string `json:"param"`
+	Value string `json:"value"`
+	// Constraint describes values accepted for the parameter
+	Constraint string `json:"constraint"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s %q, must be %s", ErrInvalidParameterValue.Error(), e.Param, e.Value, e.Constraint)
+}
+
+// Is makes validation errors match ErrInvalidParameterValue
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidParameterValue
+}
+
+// ValidationErrors are all validation errors found in a request, in order of parsing
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is makes validation errors match ErrInvalidParameterValue
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidParameterValue
+}
+
+func invalidParam(name, value, constraint string) *ValidationError {
+	return &ValidationError{Param: name, Value: value, Constraint: constraint}
+}
@@ -2,10 +2,14 @@ package request
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
 )
 
 const (
@@ -17,16 +21,99 @@ const (
 
 	offsetParamName = "offset"
 	maxOffset       = 10 * 1000
+
+	debugParamName = "debug"
+
+	userIDParamName  = "user_id"
+	userIDHeaderName = "X-User-ID"
+	maxUserIDLength  = 128
+
+	localeParamName  = "locale"
+	localeHeaderName = "Accept-Language"
+
+	deviceParamName  = "device"
+	deviceHeaderName = "X-Device-Type"
+
+	appVersionParamName  = "app_version"
+	appVersionHeaderName = "X-App-Version"
+
+	fieldsParamName = "fields"
+	formatParamName = "format"
+
+	shapeParamName = "shape"
+	shapeFull      = "full"
+	shapeLegacy    = "legacy"
+
+	includeParamName = "include"
+	excludeParamName = "exclude"
+	maxCategories    = 20
+
+	lastEventIDHeaderName = "Last-Event-ID"
+
+	// remoteAddrParamName names the client address in validation errors, it isn't a request parameter
+	remoteAddrParamName = "remote_addr"
+)
+
+// constraints of parameter values reported in validation errors
+const (
+	countConstraint       = "integer from 0 to %d"
+	offsetConstraint      = "integer from 0 to 10000"
+	debugConstraint       = "boolean"
+	userIDConstraint      = "up to 128 letters, digits, '.', '_' or '-'"
+	localeConstraint      = "language tag, e.g. en-GB"
+	deviceConstraint      = "one of phone, tablet, desktop, tv or other"
+	appVersionConstraint  = "version number, e.g. 1.2.3"
+	categoriesConstraint  = "comma separated list of up to 20 categories of lowercase letters, digits, '_' or '-'"
+	shapeConstraint       = "one of full or legacy"
+	formatConstraint      = "up to 16 lowercase letters, digits or '-'"
+	lastEventIDConstraint = "non-negative integer"
+	remoteAddrConstraint  = "host:port"
 )
 
 var (
 	ErrInvalidParameterValue = errors.New("invalid parameter")
+
+	userIDPattern     = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	localePattern     = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	appVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,3}$`)
+	categoryPattern   = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+	formatPattern     = regexp.MustCompile(`^[a-z0-9-]{1,16}$`)
+
+	devices = map[string]bool{
+		"phone":   true,
+		"tablet":  true,
+		"desktop": true,
+		"tv":      true,
+		"other":   true,
+	}
 )
 
 type Request struct {
 	Count  uint64
 	Offset uint64
 	UserIP net.IP
+	Debug  bool
+
+	// optional user details, used to personalise content
+	UserID     string
+	Locale     string
+	Device     string
+	AppVersion string
+
+	// content categories to include or exclude, lowercase
+	Include []string
+	Exclude []string
+
+	// Legacy asks for content items without fields added after the first version of the API
+	Legacy bool
+	// Fields selects content item fields to respond with, all fields are returned if empty
+	Fields []string
+	// Format is the name of the response format, the format is negotiated with Accept header if empty
+	Format string
+
+	// Resume is set when a client reconnects to an event stream, LastEventID is the ID of the last event it received
+	Resume      bool
+	LastEventID uint64
 }
 
 func NewRequest(count, offset uint64, ip net.IP) *Request {
@@ -37,17 +124,75 @@ func NewRequest(count, offset uint64, ip net.IP) *Request {
 	}
 }
 
+// WithPage returns a copy of the request for count items starting from offset,
+// validated the same way as count and offset parameters
+func (r Request) WithPage(count, offset int) (Request, error) {
+	var errs ValidationErrors
+	if count < 0 || count > maxCount {
+		errs = append(errs, invalidParam(countParamName, strconv.Itoa(count), fmt.Sprintf(countConstraint, maxCount)))
+	}
+	if offset < 0 || offset > maxOffset {
+		errs = append(errs, invalidParam(offsetParamName, strconv.Itoa(offset), offsetConstraint))
+	}
+
+	if len(errs) > 0 {
+		return r, errs
+	}
+
+	if count == 0 {
+		count = defaultCount
+	}
+	r.Count, r.Offset = uint64(count), uint64(offset)
+
+	return r, nil
+}
+
+// LimitCount validates count against a lower maximum than the default one,
+// returns ValidationErrors if count is over it
+func (r Request) LimitCount(max int) error {
+	if max >= 0 && r.Count <= uint64(max) {
+		return nil
+	}
+
+	return ValidationErrors{invalidParam(countParamName, strconv.FormatUint(r.Count, 10), fmt.Sprintf(countConstraint, max))}
+}
+
+// Parse parses and validates all request parameters,
+// returns ValidationErrors listing every invalid parameter
 func (r *Request) Parse(httpRequest *http.Request) error {
-	if err := r.parseCount(httpRequest); err != nil {
-		return err
+	parsers := []func(*http.Request) error{
+		r.parseCount,
+		r.parseOffset,
+		r.parseUserIP,
+		r.parseDebug,
+		r.parseUserID,
+		r.parseLocale,
+		r.parseDevice,
+		r.parseAppVersion,
+		r.parseInclude,
+		r.parseExclude,
+		r.parseShape,
+		r.parseFields,
+		r.parseFormat,
 	}
 
-	if err := r.parseOffset(httpRequest); err != nil {
-		return err
+	var errs ValidationErrors
+	for _, parse := range parsers {
+		err := parse(httpRequest)
+		if err == nil {
+			continue
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return err
+		}
+
+		errs = append(errs, validationErr)
 	}
 
-	if err := r.parseUserIP(httpRequest); err != nil {
-		return err
+	if len(errs) > 0 {
+		return errs
 	}
 
 	return nil
@@ -58,7 +203,7 @@ func (r *Request) parseCount(req *http.Request) error {
 
 	r.Count, err = r.queryParamUint64(req, countParamName)
 	if err != nil || r.Count > maxCount {
-		return ErrInvalidParameterValue
+		return invalidParam(countParamName, req.URL.Query().Get(countParamName), fmt.Sprintf(countConstraint, maxCount))
 	}
 	if r.Count == 0 {
 		r.Count = defaultCount
@@ -72,12 +217,161 @@ func (r *Request) parseOffset(req *http.Request) error {
 
 	r.Offset, err = r.queryParamUint64(req, offsetParamName)
 	if err != nil || r.Offset > maxOffset {
-		return ErrInvalidParameterValue
+		return invalidParam(offsetParamName, req.URL.Query().Get(offsetParamName), offsetConstraint)
+	}
+
+	return nil
+}
+
+func (r *Request) parseDebug(req *http.Request) error {
+	value := strings.TrimSpace(req.URL.Query().Get(debugParamName))
+	if value == "" {
+		return nil
+	}
+
+	var err error
+
+	r.Debug, err = strconv.ParseBool(value)
+	if err != nil {
+		return invalidParam(debugParamName, value, debugConstraint)
+	}
+
+	return nil
+}
+
+func (r *Request) parseUserID(req *http.Request) error {
+	r.UserID = paramOrHeader(req, userIDParamName, userIDHeaderName)
+	if r.UserID != "" && (len(r.UserID) > maxUserIDLength || !userIDPattern.MatchString(r.UserID)) {
+		return invalidParam(userIDParamName, r.UserID, userIDConstraint)
+	}
+
+	return nil
+}
+
+func (r *Request) parseLocale(req *http.Request) error {
+	r.Locale = strings.TrimSpace(req.URL.Query().Get(localeParamName))
+	if r.Locale != "" && !localePattern.MatchString(r.Locale) {
+		return invalidParam(localeParamName, r.Locale, localeConstraint)
+	}
+
+	// only the most preferred language is used from Accept-Language header,
+	// the header is sent by clients automatically, so an invalid value is ignored rather than rejected
+	if r.Locale == "" {
+		r.Locale = req.Header.Get(localeHeaderName)
+		r.Locale = strings.TrimSpace(strings.SplitN(strings.SplitN(r.Locale, ",", 2)[0], ";", 2)[0])
+		if !localePattern.MatchString(r.Locale) {
+			r.Locale = ""
+		}
+	}
+
+	return nil
+}
+
+func (r *Request) parseDevice(req *http.Request) error {
+	r.Device = strings.ToLower(paramOrHeader(req, deviceParamName, deviceHeaderName))
+	if r.Device != "" && !devices[r.Device] {
+		return invalidParam(deviceParamName, r.Device, deviceConstraint)
 	}
 
 	return nil
 }
 
+func (r *Request) parseAppVersion(req *http.Request) error {
+	r.AppVersion = paramOrHeader(req, appVersionParamName, appVersionHeaderName)
+	if r.AppVersion != "" && !appVersionPattern.MatchString(r.AppVersion) {
+		return invalidParam(appVersionParamName, r.AppVersion, appVersionConstraint)
+	}
+
+	return nil
+}
+
+func (r *Request) parseInclude(req *http.Request) error {
+	var err error
+
+	r.Include, err = queryParamCategories(req, includeParamName)
+
+	return err
+}
+
+func (r *Request) parseExclude(req *http.Request) error {
+	var err error
+
+	r.Exclude, err = queryParamCategories(req, excludeParamName)
+
+	return err
+}
+
+func (r *Request) parseShape(req *http.Request) error {
+	value := req.URL.Query().Get(shapeParamName)
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", shapeFull:
+		r.Legacy = false
+	case shapeLegacy:
+		r.Legacy = true
+	default:
+		return invalidParam(shapeParamName, value, shapeConstraint)
+	}
+
+	return nil
+}
+
+func (r *Request) parseFields(req *http.Request) error {
+	value := strings.TrimSpace(req.URL.Query().Get(fieldsParamName))
+	if value == "" {
+		return nil
+	}
+
+	// all unknown fields are reported at once, so clients can fix them in one go
+	var unknown []string
+
+	r.Fields = nil
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !provider.IsItemField(field) {
+			unknown = append(unknown, field)
+
+			continue
+		}
+
+		r.Fields = append(r.Fields, field)
+	}
+
+	if len(unknown) > 0 {
+		return invalidParam(fieldsParamName, strings.Join(unknown, ","), "comma separated list of "+strings.Join(provider.ItemFields, ", "))
+	}
+
+	return nil
+}
+
+func (r *Request) parseFormat(req *http.Request) error {
+	r.Format = strings.ToLower(strings.TrimSpace(req.URL.Query().Get(formatParamName)))
+	if r.Format != "" && !formatPattern.MatchString(r.Format) {
+		return invalidParam(formatParamName, r.Format, formatConstraint)
+	}
+
+	return nil
+}
+
+// ParseLastEventID parses Last-Event-ID header sent by clients reconnecting to an event stream.
+// It isn't part of Parse, as the header means nothing outside of streams. Returns ValidationErrors if invalid.
+func (r *Request) ParseLastEventID(req *http.Request) error {
+	value := strings.TrimSpace(req.Header.Get(lastEventIDHeaderName))
+	if value == "" {
+		return nil
+	}
+
+	var err error
+
+	r.LastEventID, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return ValidationErrors{invalidParam(lastEventIDHeaderName, value, lastEventIDConstraint)}
+	}
+	r.Resume = true
+
+	return nil
+}
+
 func (r *Request) parseUserIP(req *http.Request) error {
 	forwardedForHeader := req.Header.Get(forwardedForHeaderName)
 
@@ -91,7 +385,7 @@ func (r *Request) parseUserIP(req *http.Request) error {
 
 	ip, _, err := net.SplitHostPort(req.RemoteAddr)
 	if err != nil {
-		return err
+		return invalidParam(remoteAddrParamName, req.RemoteAddr, remoteAddrConstraint)
 	}
 
 	r.UserIP = net.ParseIP(ip)
@@ -113,3 +407,39 @@ func (r *Request) queryParamUint64(req *http.Request, key string) (uint64, error
 
 	return 0, nil
 }
+
+// paramOrHeader returns the query parameter value, falling back to the header value if the parameter isn't passed
+func paramOrHeader(req *http.Request, paramName, headerName string) string {
+	if value := strings.TrimSpace(req.URL.Query().Get(paramName)); value != "" {
+		return value
+	}
+
+	return strings.TrimSpace(req.Header.Get(headerName))
+}
+
+// queryParamCategories parses a comma separated list of categories
+func queryParamCategories(req *http.Request, key string) ([]string, error) {
+	value := strings.TrimSpace(req.URL.Query().Get(key))
+	if value == "" {
+		return nil, nil
+	}
+
+	categories := strings.Split(strings.ToLower(value), ",")
+	if len(categories) > maxCategories {
+		return nil, invalidParam(key, value, categoriesConstraint)
+	}
+
+	var invalid []string
+	for i := range categories {
+		categories[i] = strings.TrimSpace(categories[i])
+		if !categoryPattern.MatchString(categories[i]) {
+			invalid = append(invalid, categories[i])
+		}
+	}
+
+	if len(invalid) > 0 {
+		return nil, invalidParam(key, strings.Join(invalid, ","), categoriesConstraint)
+	}
+
+	return categories, nil
+}
@@ -1,9 +1,11 @@
 package request
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -21,6 +23,7 @@ func TestRequest_Parse(t *testing.T) {
 		request        func() *http.Request
 		expectedResult *Request
 		expectedError  error
+		expectedParams []string
 	}{
 		{
 			name:           "Count not passed",
@@ -33,9 +36,10 @@ func TestRequest_Parse(t *testing.T) {
 			expectedResult: NewRequest(10, 0, defaultIP),
 		},
 		{
-			name:          "Count over maximum",
-			request:       func() *http.Request { return defaultHTTPRequest("/?count=101") },
-			expectedError: ErrInvalidParameterValue,
+			name:           "Count over maximum",
+			request:        func() *http.Request { return defaultHTTPRequest("/?count=101") },
+			expectedError:  ErrInvalidParameterValue,
+			expectedParams: []string{countParamName},
 		},
 		{
 			name:          "Count invalid",
@@ -53,9 +57,43 @@ func TestRequest_Parse(t *testing.T) {
 			expectedResult: NewRequest(defaultCount, 10, defaultIP),
 		},
 		{
-			name:          "Offset over maximum",
-			request:       func() *http.Request { return defaultHTTPRequest("/?offset=10001") },
-			expectedError: ErrInvalidParameterValue,
+			name:           "Offset over maximum",
+			request:        func() *http.Request { return defaultHTTPRequest("/?offset=10001") },
+			expectedError:  ErrInvalidParameterValue,
+			expectedParams: []string{offsetParamName},
+		},
+		{
+			name:           "Count and offset invalid",
+			request:        func() *http.Request { return defaultHTTPRequest("/?count=test&offset=10001") },
+			expectedError:  ErrInvalidParameterValue,
+			expectedParams: []string{countParamName, offsetParamName},
+		},
+		{
+			name: "Remote address invalid",
+			request: func() *http.Request {
+				req := defaultHTTPRequest("/")
+				req.RemoteAddr = "invalid"
+
+				return req
+			},
+			expectedError:  ErrInvalidParameterValue,
+			expectedParams: []string{remoteAddrParamName},
+		},
+		{
+			name: "Last event ID ignored",
+			request: func() *http.Request {
+				req := defaultHTTPRequest("/")
+				req.Header.Set(lastEventIDHeaderName, "last")
+
+				return req
+			},
+			expectedResult: NewRequest(defaultCount, 0, defaultIP),
+		},
+		{
+			name:           "User details and categories invalid",
+			request:        func() *http.Request { return defaultHTTPRequest("/?device=fridge&app_version=x&include=A!&exclude=B!") },
+			expectedError:  ErrInvalidParameterValue,
+			expectedParams: []string{deviceParamName, appVersionParamName, includeParamName, excludeParamName},
 		},
 		{
 			name:          "Offset invalid",
@@ -97,6 +135,123 @@ func TestRequest_Parse(t *testing.T) {
 			},
 			expectedResult: NewRequest(defaultCount, 0, defaultIP),
 		},
+		{
+			name:           "Debug valid",
+			request:        func() *http.Request { return defaultHTTPRequest("/?debug=1") },
+			expectedResult: &Request{Count: defaultCount, UserIP: defaultIP, Debug: true},
+		},
+		{
+			name: "User details from query parameters",
+			request: func() *http.Request {
+				return defaultHTTPRequest("/?user_id=user-1&locale=en-GB&device=Phone&app_version=1.2.3")
+			},
+			expectedResult: &Request{
+				Count:      defaultCount,
+				UserIP:     defaultIP,
+				UserID:     "user-1",
+				Locale:     "en-GB",
+				Device:     "phone",
+				AppVersion: "1.2.3",
+			},
+		},
+		{
+			name: "User details from headers",
+			request: func() *http.Request {
+				req := defaultHTTPRequest("/")
+				req.Header.Add(userIDHeaderName, "user-1")
+				req.Header.Add(localeHeaderName, "de-CH, fr;q=0.9, en;q=0.8")
+				req.Header.Add(deviceHeaderName, "tablet")
+				req.Header.Add(appVersionHeaderName, "2.0")
+
+				return req
+			},
+			expectedResult: &Request{
+				Count:      defaultCount,
+				UserIP:     defaultIP,
+				UserID:     "user-1",
+				Locale:     "de-CH",
+				Device:     "tablet",
+				AppVersion: "2.0",
+			},
+		},
+		{
+			name:          "User ID invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?user_id=user%201") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name: "Locale header invalid",
+			request: func() *http.Request {
+				req := defaultHTTPRequest("/")
+				req.Header.Add(localeHeaderName, "en_US")
+
+				return req
+			},
+			expectedResult: &Request{
+				Count:  defaultCount,
+				UserIP: defaultIP,
+			},
+		},
+		{
+			name:          "Locale invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?locale=english") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:          "Device invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?device=watch") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:          "App version invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?app_version=v1") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:    "Categories valid",
+			request: func() *http.Request { return defaultHTTPRequest("/?include=Sports,%20tech&exclude=politics") },
+			expectedResult: &Request{
+				Count:   defaultCount,
+				UserIP:  defaultIP,
+				Include: []string{"sports", "tech"},
+				Exclude: []string{"politics"},
+			},
+		},
+		{
+			name:          "Categories invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?exclude=politics,,sports") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:           "Legacy shape",
+			request:        func() *http.Request { return defaultHTTPRequest("/?shape=legacy") },
+			expectedResult: &Request{Count: defaultCount, UserIP: defaultIP, Legacy: true},
+		},
+		{
+			name:          "Shape invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?shape=compact") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:           "Fields valid",
+			request:        func() *http.Request { return defaultHTTPRequest("/?fields=id,%20Title,link") },
+			expectedResult: &Request{Count: defaultCount, UserIP: defaultIP, Fields: []string{"id", "title", "link"}},
+		},
+		{
+			name:          "Fields unknown",
+			request:       func() *http.Request { return defaultHTTPRequest("/?fields=id,body") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:          "Format invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?format=json%3B") },
+			expectedError: ErrInvalidParameterValue,
+		},
+		{
+			name:          "Debug invalid",
+			request:       func() *http.Request { return defaultHTTPRequest("/?debug=test") },
+			expectedError: ErrInvalidParameterValue,
+		},
 	}
 
 	for _, test := range testCases {
@@ -105,10 +260,26 @@ func TestRequest_Parse(t *testing.T) {
 			err := request.Parse(test.request())
 
 			// check error returned
-			if test.expectedError != nil && test.expectedError != err {
+			if test.expectedError != nil && !errors.Is(err, test.expectedError) || test.expectedError == nil && err != nil {
 				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
 			}
 
+			// check names of invalid parameters
+			if test.expectedParams != nil {
+				var validationErrs ValidationErrors
+				if !errors.As(err, &validationErrs) {
+					t.Fatalf("params check failed: expected to get '%v', but got '%v'", test.expectedParams, err)
+				}
+
+				params := make([]string, len(validationErrs))
+				for i := range validationErrs {
+					params[i] = validationErrs[i].Param
+				}
+				if strings.Join(params, ",") != strings.Join(test.expectedParams, ",") {
+					t.Fatalf("params check failed: expected to get '%v', but got '%v'", test.expectedParams, params)
+				}
+			}
+
 			if test.expectedResult != nil {
 				// check count
 				if request.Count != test.expectedResult.Count {
@@ -120,6 +291,35 @@ func TestRequest_Parse(t *testing.T) {
 					t.Fatalf("offset check failed: expected to get '%v', but got '%v'", test.expectedResult.Offset, request.Offset)
 				}
 
+				// check debug mode
+				if request.Debug != test.expectedResult.Debug {
+					t.Fatalf("debug check failed: expected to get '%v', but got '%v'", test.expectedResult.Debug, request.Debug)
+				}
+
+				// check user details
+				if request.UserID != test.expectedResult.UserID ||
+					request.Locale != test.expectedResult.Locale ||
+					request.Device != test.expectedResult.Device ||
+					request.AppVersion != test.expectedResult.AppVersion {
+					t.Fatalf("user details check failed: expected to get '%+v', but got '%+v'", test.expectedResult, request)
+				}
+
+				// check categories
+				if strings.Join(request.Include, ",") != strings.Join(test.expectedResult.Include, ",") ||
+					strings.Join(request.Exclude, ",") != strings.Join(test.expectedResult.Exclude, ",") {
+					t.Fatalf("categories check failed: expected to get '%+v', but got '%+v'", test.expectedResult, request)
+				}
+
+				// check shape
+				if request.Legacy != test.expectedResult.Legacy {
+					t.Fatalf("shape check failed: expected to get '%v', but got '%v'", test.expectedResult.Legacy, request.Legacy)
+				}
+
+				// check fields
+				if strings.Join(request.Fields, ",") != strings.Join(test.expectedResult.Fields, ",") {
+					t.Fatalf("fields check failed: expected to get '%v', but got '%v'", test.expectedResult.Fields, request.Fields)
+				}
+
 				// check user IP
 				if request.UserIP.String() != test.expectedResult.UserIP.String() {
 					t.Fatalf("user IP check failed: expected to get '%v', but got '%v'", test.expectedResult.UserIP.String(), request.UserIP.String())
@@ -128,3 +328,67 @@ func TestRequest_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestRequest_ParseLastEventID(t *testing.T) {
+	testCases := []struct {
+		name           string
+		header         string
+		expectedResume bool
+		expectedID     uint64
+		expectedError  error
+	}{
+		{name: "Not passed"},
+		{name: "Valid", header: "9", expectedResume: true, expectedID: 9},
+		{name: "Invalid", header: "last", expectedError: ErrInvalidParameterValue},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			httpReq := httptest.NewRequest(http.MethodGet, "/stream", nil)
+			if test.header != "" {
+				httpReq.Header.Set(lastEventIDHeaderName, test.header)
+			}
+
+			request := Request{}
+			err := request.ParseLastEventID(httpReq)
+
+			if test.expectedError != nil && !errors.Is(err, test.expectedError) || test.expectedError == nil && err != nil {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+
+			if request.Resume != test.expectedResume || request.LastEventID != test.expectedID {
+				t.Fatalf("last event ID check failed: expected to get '%v', but got '%v'", test.expectedID, request.LastEventID)
+			}
+		})
+	}
+}
+
+func TestRequest_LimitCount(t *testing.T) {
+	testCases := []struct {
+		name          string
+		count         uint64
+		max           int
+		expectedError error
+	}{
+		{name: "Under maximum", count: 5, max: 10},
+		{name: "At maximum", count: 10, max: 10},
+		{name: "Over maximum", count: 11, max: 10, expectedError: ErrInvalidParameterValue},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			request := Request{Count: test.count}
+			err := request.LimitCount(test.max)
+
+			if test.expectedError != nil && !errors.Is(err, test.expectedError) || test.expectedError == nil && err != nil {
+				t.Fatalf("err check failed: expected to get '%v', but got '%v'", test.expectedError, err)
+			}
+
+			var validationErrs ValidationErrors
+			if err != nil && (!errors.As(err, &validationErrs) || validationErrs[0].Param != countParamName ||
+				validationErrs[0].Constraint != "integer from 0 to 10") {
+				t.Fatalf("param check failed: expected to get '%v', but got '%v'", countParamName, err)
+			}
+		})
+	}
+}
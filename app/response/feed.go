@@ -0,0 +1,211 @@
+package response
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"time"
+)
+
+// Channel describes the feed served as RSS or Atom
+type Channel struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+}
+
+// RSS writes the response as an RSS 2.0 feed, selected fields are ignored
+type RSS struct {
+	Channel Channel
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           *int      `xml:"ttl,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (RSS) Format() string { return "rss" }
+
+func (RSS) ContentTypes() []string { return []string{"application/rss+xml"} }
+
+func (f RSS) Serialize(w io.Writer, resp Response, _ []string) error {
+	lastBuildDate := ""
+	if latest, ok := latestPublication(resp); ok {
+		lastBuildDate = latest.Format(time.RFC1123Z)
+	}
+
+	doc := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Channel.Title,
+			Link:          f.Channel.Link,
+			Description:   f.Channel.Description,
+			Language:      f.Channel.Language,
+			LastBuildDate: lastBuildDate,
+			TTL:           ttl(resp),
+			Items:         make([]rssItem, len(resp)),
+		},
+	}
+
+	for i := range resp {
+		doc.Channel.Items[i] = rssItem{
+			Title:       resp[i].Title,
+			Link:        resp[i].Link,
+			Description: resp[i].Summary,
+			GUID:        rssGUID{Value: resp[i].Source + ":" + resp[i].ID},
+			Categories:  resp[i].Categories,
+		}
+		if resp[i].PublishedAt != nil {
+			doc.Channel.Items[i].PubDate = resp[i].PublishedAt.Format(time.RFC1123Z)
+		}
+	}
+
+	return writeXML(w, doc)
+}
+
+// Atom writes the response as an Atom feed, selected fields are ignored
+type Atom struct {
+	Channel Channel
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Link     atomLink    `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Link       *atomLink      `xml:"link,omitempty"`
+	Summary    string         `xml:"summary,omitempty"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+func (Atom) Format() string { return "atom" }
+
+func (Atom) ContentTypes() []string { return []string{"application/atom+xml"} }
+
+func (f Atom) Serialize(w io.Writer, resp Response, _ []string) error {
+	// the feed has to be updated at some point, the epoch is used when no item has a publication time
+	updated, ok := latestPublication(resp)
+	if !ok {
+		updated = time.Unix(0, 0).UTC()
+	}
+
+	feed := atomFeed{
+		Lang:     f.Channel.Language,
+		ID:       f.Channel.Link,
+		Title:    f.Channel.Title,
+		Subtitle: f.Channel.Description,
+		Updated:  updated.Format(time.RFC3339),
+		Link:     atomLink{Href: f.Channel.Link},
+		Author:   atomAuthor{Name: f.Channel.Title},
+		Entries:  make([]atomEntry, len(resp)),
+	}
+
+	for i := range resp {
+		entryUpdated := updated
+		if resp[i].PublishedAt != nil {
+			entryUpdated = *resp[i].PublishedAt
+		}
+
+		entry := atomEntry{
+			ID:      "urn:content:" + resp[i].Source + ":" + resp[i].ID,
+			Title:   resp[i].Title,
+			Updated: entryUpdated.Format(time.RFC3339),
+			Summary: resp[i].Summary,
+		}
+		if resp[i].Link != "" {
+			entry.Link = &atomLink{Href: resp[i].Link}
+		}
+		if resp[i].Author != "" {
+			entry.Author = &atomAuthor{Name: resp[i].Author}
+		}
+		for _, category := range resp[i].Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+
+		feed.Entries[i] = entry
+	}
+
+	return writeXML(w, feed)
+}
+
+// latestPublication returns the publication time of the most recently published item,
+// ok is false if none of the items has one. Feed timestamps are derived from items, so ETags stay stable.
+func latestPublication(resp Response) (latest time.Time, ok bool) {
+	for i := range resp {
+		if resp[i].PublishedAt != nil && (!ok || resp[i].PublishedAt.After(latest)) {
+			latest, ok = *resp[i].PublishedAt, true
+		}
+	}
+
+	return latest, ok
+}
+
+// ttl returns the number of minutes until the earliest item expires, or nil for an empty response
+func ttl(resp Response) *int {
+	earliest, ok := resp.EarliestExpiry()
+	if !ok {
+		return nil
+	}
+
+	minutes := int(math.Max(math.Ceil(time.Until(earliest).Minutes()), 0))
+
+	return &minutes
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(doc)
+}
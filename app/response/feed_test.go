@@ -0,0 +1,87 @@
+package response
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestFeeds_Serialize(t *testing.T) {
+	publishedAt := time.Date(2020, 9, 24, 10, 47, 11, 0, time.UTC)
+	resp := Response{
+		{ID: "1", Title: "First", Source: "1", Link: "https://1.com/1", Expiry: time.Now().Add(time.Hour), PublishedAt: &publishedAt},
+		{ID: "2", Title: "Second", Source: "2", Expiry: time.Now().Add(10 * time.Minute), Author: "Author"},
+	}
+	channel := Channel{Title: "News", Link: "https://news.com/", Description: "Latest news"}
+
+	t.Run("RSS", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		if err := (RSS{Channel: channel}).Serialize(buffer, resp, nil); err != nil {
+			t.Fatalf("unexpected error '%v'", err)
+		}
+
+		doc := rssDocument{}
+		if err := xml.Unmarshal(buffer.Bytes(), &doc); err != nil {
+			t.Fatalf("couldn't decode feed: %v", err)
+		}
+
+		if doc.Version != "2.0" || doc.Channel.Title != channel.Title || len(doc.Channel.Items) != 2 {
+			t.Fatalf("Got channel %+v, want version 2.0 channel '%s' with 2 items", doc.Channel, channel.Title)
+		}
+		if doc.Channel.TTL == nil || *doc.Channel.TTL != 10 {
+			t.Errorf("Got ttl %v, want 10", doc.Channel.TTL)
+		}
+		if doc.Channel.LastBuildDate != "Thu, 24 Sep 2020 10:47:11 +0000" {
+			t.Errorf("Got lastBuildDate %v, want 'Thu, 24 Sep 2020 10:47:11 +0000'", doc.Channel.LastBuildDate)
+		}
+		if doc.Channel.Items[0].PubDate != "Thu, 24 Sep 2020 10:47:11 +0000" {
+			t.Errorf("Got pubDate %v, want 'Thu, 24 Sep 2020 10:47:11 +0000'", doc.Channel.Items[0].PubDate)
+		}
+	})
+
+	t.Run("Atom", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		if err := (Atom{Channel: channel}).Serialize(buffer, resp, nil); err != nil {
+			t.Fatalf("unexpected error '%v'", err)
+		}
+
+		feed := atomFeed{}
+		if err := xml.Unmarshal(buffer.Bytes(), &feed); err != nil {
+			t.Fatalf("couldn't decode feed: %v", err)
+		}
+
+		if feed.ID != channel.Link || len(feed.Entries) != 2 {
+			t.Fatalf("Got feed %+v, want feed '%s' with 2 entries", feed, channel.Link)
+		}
+		if feed.Updated != "2020-09-24T10:47:11Z" || feed.Entries[1].Updated != "2020-09-24T10:47:11Z" {
+			t.Errorf("Got feed updated at %v and entry updated at %v, want 2020-09-24T10:47:11Z", feed.Updated, feed.Entries[1].Updated)
+		}
+		if feed.Entries[0].ID != "urn:content:1:1" || feed.Entries[0].Updated != "2020-09-24T10:47:11Z" {
+			t.Errorf("Got entry %+v, want ID 'urn:content:1:1' updated at 2020-09-24T10:47:11Z", feed.Entries[0])
+		}
+		if feed.Entries[1].Author == nil || feed.Entries[1].Author.Name != "Author" {
+			t.Errorf("Got entry author %+v, want 'Author'", feed.Entries[1].Author)
+		}
+	})
+}
+
+// feeds have to be byte for byte the same for the same items, otherwise ETags never match
+func TestFeeds_SerializeStable(t *testing.T) {
+	resp := Response{{ID: "1", Title: "First", Source: "1", Expiry: time.Now().Add(time.Hour)}}
+
+	for _, serializer := range []Serializer{RSS{}, Atom{}} {
+		first, second := &bytes.Buffer{}, &bytes.Buffer{}
+		if err := serializer.Serialize(first, resp, nil); err != nil {
+			t.Fatalf("%s: unexpected error '%v'", serializer.Format(), err)
+		}
+		time.Sleep(time.Second)
+		if err := serializer.Serialize(second, resp, nil); err != nil {
+			t.Fatalf("%s: unexpected error '%v'", serializer.Format(), err)
+		}
+
+		if first.String() != second.String() {
+			t.Errorf("%s: got different output for the same items: %q and %q", serializer.Format(), first.String(), second.String())
+		}
+	}
+}
@@ -1,5 +1,87 @@
 package response
 
-import "github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+import (
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
 
 type Response []provider.ContentItem
+
+// EarliestExpiry returns the expiry of the item which expires first, ok is false for an empty response
+func (r Response) EarliestExpiry() (earliest time.Time, ok bool) {
+	for i := range r {
+		if !ok || r[i].Expiry.Before(earliest) {
+			earliest, ok = r[i].Expiry, true
+		}
+	}
+
+	return earliest, ok
+}
+
+// FieldValue returns the value of the content item field with the given name,
+// or nil if there's no such field or the field is optional and empty
+func FieldValue(item provider.ContentItem, name string) interface{} {
+	switch name {
+	case "id":
+		return item.ID
+	case "title":
+		return item.Title
+	case "source":
+		return item.Source
+	case "summary":
+		return item.Summary
+	case "link":
+		return item.Link
+	case "expiry":
+		return item.Expiry
+	case "images":
+		if len(item.Images) == 0 {
+			return nil
+		}
+
+		return item.Images
+	case "author":
+		if item.Author == "" {
+			return nil
+		}
+
+		return item.Author
+	case "published_at":
+		if item.PublishedAt == nil {
+			return nil
+		}
+
+		return *item.PublishedAt
+	case "language":
+		if item.Language == "" {
+			return nil
+		}
+
+		return item.Language
+	case "categories":
+		if len(item.Categories) == 0 {
+			return nil
+		}
+
+		return item.Categories
+	default:
+		return nil
+	}
+}
+
+// Select returns the response with only the given fields of each item,
+// empty optional fields are omitted the same way as in the JSON representation of content items
+func (r Response) Select(fields []string) []map[string]interface{} {
+	res := make([]map[string]interface{}, len(r))
+	for i := range r {
+		res[i] = make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value := FieldValue(r[i], field); value != nil {
+				res[i][field] = value
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,320 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+// Serializer writes a response in a particular format
+type Serializer interface {
+	// Format is the name of the format used in the format request parameter
+	Format() string
+	// ContentTypes are media types the serializer produces, the first one is used in the Content-Type header
+	ContentTypes() []string
+	// Serialize writes the response, only the given fields are written if fields isn't empty
+	Serialize(w io.Writer, resp Response, fields []string) error
+}
+
+// Serializers is a list of supported formats, the first one is the default
+type Serializers []Serializer
+
+// DefaultSerializers are formats supported out of the box
+var DefaultSerializers = Serializers{JSON{}, NDJSON{}, XML{}, CSV{}}
+
+// ByFormat returns the serializer with the given format name, or nil if there isn't one
+func (s Serializers) ByFormat(format string) Serializer {
+	for i := range s {
+		if s[i].Format() == format {
+			return s[i]
+		}
+	}
+
+	return nil
+}
+
+// Negotiate picks the serializer best matching the Accept header, or returns nil if none of them is acceptable
+func (s Serializers) Negotiate(accept string) Serializer {
+	if len(s) == 0 {
+		return nil
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return s[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	// ranges with q=0 exclude matching types from less specific ranges, e.g. application/json;q=0 from */*
+	var ranges, excluded []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		r := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), quality: 1}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					r.quality = q
+				}
+			}
+		}
+
+		switch {
+		case r.mediaType == "":
+		case r.quality > 0:
+			ranges = append(ranges, r)
+		default:
+			excluded = append(excluded, r)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	isExcluded := func(r mediaRange, contentType string) bool {
+		for _, e := range excluded {
+			if mediaTypeMatches(e.mediaType, contentType) && specificity(e.mediaType) >= specificity(r.mediaType) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, r := range ranges {
+		for i := range s {
+			for _, contentType := range s[i].ContentTypes() {
+				if mediaTypeMatches(r.mediaType, contentType) && !isExcluded(r, contentType) {
+					return s[i]
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// specificity ranks media ranges, exact types are more specific than type/* and type/* more than */*
+func specificity(mediaRange string) int {
+	switch {
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func mediaTypeMatches(mediaRange, contentType string) bool {
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(mediaRange, "*"))
+}
+
+// JSON writes the response as a JSON array
+type JSON struct{}
+
+func (JSON) Format() string { return "json" }
+
+func (JSON) ContentTypes() []string { return []string{"application/json"} }
+
+func (JSON) Serialize(w io.Writer, resp Response, fields []string) error {
+	if len(fields) == 0 {
+		return json.NewEncoder(w).Encode(resp)
+	}
+
+	return json.NewEncoder(w).Encode(resp.Select(fields))
+}
+
+// NDJSON writes every content item as a JSON object on its own line
+type NDJSON struct{}
+
+func (NDJSON) Format() string { return "ndjson" }
+
+func (NDJSON) ContentTypes() []string { return []string{"application/x-ndjson", "application/ndjson"} }
+
+func (NDJSON) Serialize(w io.Writer, resp Response, fields []string) error {
+	encoder := json.NewEncoder(w)
+
+	for i := range resp {
+		var item interface{} = resp[i]
+		if len(fields) > 0 {
+			item = resp[i : i+1].Select(fields)[0]
+		}
+
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// XML writes the response as an <items> document with an <item> element per content item
+type XML struct{}
+
+func (XML) Format() string { return "xml" }
+
+func (XML) ContentTypes() []string { return []string{"application/xml", "text/xml"} }
+
+func (XML) Serialize(w io.Writer, resp Response, fields []string) error {
+	if len(fields) == 0 {
+		fields = provider.ItemFields
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+
+	items := xml.StartElement{Name: xml.Name{Local: "items"}}
+	if err := encoder.EncodeToken(items); err != nil {
+		return err
+	}
+
+	for i := range resp {
+		item := xml.StartElement{Name: xml.Name{Local: "item"}}
+		if err := encoder.EncodeToken(item); err != nil {
+			return err
+		}
+
+		for _, field := range fields {
+			value := FieldValue(resp[i], field)
+			if value == nil {
+				continue
+			}
+
+			if err := encodeXMLField(encoder, field, value); err != nil {
+				return err
+			}
+		}
+
+		if err := encoder.EncodeToken(item.End()); err != nil {
+			return err
+		}
+	}
+
+	if err := encoder.EncodeToken(items.End()); err != nil {
+		return err
+	}
+
+	return encoder.Flush()
+}
+
+func encodeXMLField(encoder *xml.Encoder, field string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: field}}
+
+	var children []xml.StartElement
+	var values []string
+
+	switch v := value.(type) {
+	case []provider.Image:
+		for i := range v {
+			child := xml.StartElement{
+				Name: xml.Name{Local: "image"},
+				Attr: []xml.Attr{{Name: xml.Name{Local: "url"}, Value: v[i].URL}},
+			}
+			if v[i].Width > 0 {
+				child.Attr = append(child.Attr, xml.Attr{Name: xml.Name{Local: "width"}, Value: strconv.Itoa(v[i].Width)})
+			}
+			if v[i].Height > 0 {
+				child.Attr = append(child.Attr, xml.Attr{Name: xml.Name{Local: "height"}, Value: strconv.Itoa(v[i].Height)})
+			}
+
+			children = append(children, child)
+			values = append(values, "")
+		}
+	case []string:
+		for i := range v {
+			children = append(children, xml.StartElement{Name: xml.Name{Local: "category"}})
+			values = append(values, v[i])
+		}
+	default:
+		return encoder.EncodeElement(formatValue(value), start)
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for i := range children {
+		if err := encoder.EncodeElement(values[i], children[i]); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// CSV writes the response as a table with a header row of field names
+type CSV struct{}
+
+func (CSV) Format() string { return "csv" }
+
+func (CSV) ContentTypes() []string { return []string{"text/csv"} }
+
+func (CSV) Serialize(w io.Writer, resp Response, fields []string) error {
+	if len(fields) == 0 {
+		fields = provider.ItemFields
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return err
+	}
+
+	record := make([]string, len(fields))
+	for i := range resp {
+		for j, field := range fields {
+			record[j] = formatValue(FieldValue(resp[i], field))
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// formatValue formats a field value as plain text, lists are separated with "|"
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case nil:
+		return ""
+	case []provider.Image:
+		urls := make([]string, len(v))
+		for i := range v {
+			urls[i] = v[i].URL
+		}
+
+		return strings.Join(urls, "|")
+	case []string:
+		return strings.Join(v, "|")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,110 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+)
+
+func TestSerializers_Negotiate(t *testing.T) {
+	testCases := []struct {
+		name           string
+		accept         string
+		expectedFormat string
+	}{
+		{name: "No Accept header", accept: "", expectedFormat: "json"},
+		{name: "Exact type", accept: "text/csv", expectedFormat: "csv"},
+		{name: "Alias type", accept: "text/xml", expectedFormat: "xml"},
+		{name: "Any type", accept: "*/*", expectedFormat: "json"},
+		{name: "Type wildcard", accept: "text/*", expectedFormat: "xml"},
+		{name: "Quality", accept: "application/json;q=0.5, application/x-ndjson", expectedFormat: "ndjson"},
+		{name: "Browser", accept: "text/html,application/xhtml+xml,*/*;q=0.8", expectedFormat: "json"},
+		{name: "Not acceptable", accept: "text/html", expectedFormat: ""},
+		{name: "Rejected type", accept: "text/csv;q=0", expectedFormat: ""},
+		{name: "Rejected type with wildcard", accept: "application/json;q=0, */*", expectedFormat: "ndjson"},
+		{name: "Rejected type wildcard", accept: "application/*;q=0, */*;q=0.5", expectedFormat: "xml"},
+		{name: "Accepted type with rejected wildcard", accept: "text/csv, text/*;q=0", expectedFormat: "csv"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			format := ""
+			if serializer := DefaultSerializers.Negotiate(test.accept); serializer != nil {
+				format = serializer.Format()
+			}
+
+			if format != test.expectedFormat {
+				t.Fatalf("format check failed: expected to get '%v', but got '%v'", test.expectedFormat, format)
+			}
+		})
+	}
+}
+
+func TestSerializers_Serialize(t *testing.T) {
+	expiry := time.Date(2020, 9, 24, 10, 47, 11, 0, time.UTC)
+	resp := Response{
+		{ID: "1", Title: "Fish & Chips", Source: "1", Expiry: expiry, Categories: []string{"food", "uk"}},
+		{ID: "2", Title: "Title, with comma", Source: "2", Expiry: expiry},
+	}
+
+	testCases := []struct {
+		format   string
+		fields   []string
+		expected string
+	}{
+		{
+			format:   "json",
+			fields:   []string{"id", "title"},
+			expected: `[{"id":"1","title":"Fish \u0026 Chips"},{"id":"2","title":"Title, with comma"}]` + "\n",
+		},
+		{
+			format:   "json",
+			fields:   []string{"id", "published_at", "categories"},
+			expected: `[{"categories":["food","uk"],"id":"1"},{"id":"2"}]` + "\n",
+		},
+		{
+			format:   "ndjson",
+			fields:   []string{"id"},
+			expected: `{"id":"1"}` + "\n" + `{"id":"2"}` + "\n",
+		},
+		{
+			format: "xml",
+			fields: []string{"id", "title", "categories"},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<items><item><id>1</id><title>Fish &amp; Chips</title><categories><category>food</category><category>uk</category></categories></item>` +
+				`<item><id>2</id><title>Title, with comma</title></item></items>`,
+		},
+		{
+			format:   "csv",
+			fields:   []string{"id", "title", "expiry", "categories"},
+			expected: "id,title,expiry,categories\n1,Fish & Chips,2020-09-24T10:47:11Z,food|uk\n2,\"Title, with comma\",2020-09-24T10:47:11Z,\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.format, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			if err := DefaultSerializers.ByFormat(test.format).Serialize(buffer, resp, test.fields); err != nil {
+				t.Fatalf("unexpected error '%v'", err)
+			}
+
+			if buffer.String() != test.expected {
+				t.Fatalf("output check failed: expected to get '%v', but got '%v'", test.expected, buffer.String())
+			}
+		})
+	}
+}
+
+// make sure images are written in all formats without errors
+func TestSerializers_SerializeImages(t *testing.T) {
+	publishedAt := time.Now()
+	resp := Response{{ID: "1", Images: []provider.Image{{URL: "https://1.com/1.jpg", Width: 10}}, PublishedAt: &publishedAt}}
+
+	for _, serializer := range DefaultSerializers {
+		if err := serializer.Serialize(&bytes.Buffer{}, resp, nil); err != nil {
+			t.Errorf("%s: unexpected error '%v'", serializer.Format(), err)
+		}
+	}
+}
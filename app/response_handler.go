@@ -1,45 +1,221 @@
 package app
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
 )
 
+const (
+	codeInvalidParameter     = "invalid_parameter"
+	codeOverloaded           = "overloaded"
+	codeDebugForbidden       = "debug_forbidden"
+	codeNotAcceptable        = "not_acceptable"
+	codeNotFound             = "not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeInvalidBatch         = "invalid_batch"
+	codeUnknownFeed          = "unknown_feed"
+	codeStreamingUnsupported = "streaming_unsupported"
+	codeInternalError        = "internal_error"
+)
+
+var (
+	ErrNotAcceptable    = errors.New("none of the acceptable response formats is supported")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// errorResponse is the body of error responses
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Param is the name of the first invalid request parameter, if any
+	Param string `json:"param,omitempty"`
+	// Errors lists all invalid request parameters
+	Errors request.ValidationErrors `json:"errors,omitempty"`
+}
+
 func handleError(w http.ResponseWriter, req *http.Request, err error) {
+	status, body := errorStatus(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if req.Method != http.MethodHead {
+		if encodeErr := json.NewEncoder(w).Encode(body); encodeErr != nil {
+			err = encodeErr
+		}
+	}
+
+	logRequest(w, req, status, err)
+}
+
+// errorStatus maps an error to the response status and body
+func errorStatus(err error) (int, errorResponse) {
 	status := http.StatusInternalServerError
-	if err == request.ErrInvalidParameterValue {
+	body := errorResponse{Code: codeInternalError, Message: "internal server error"}
+
+	switch {
+	case errors.Is(err, request.ErrInvalidParameterValue):
+		status = http.StatusBadRequest
+		body = errorResponse{Code: codeInvalidParameter, Message: err.Error()}
+
+		var validationErrs request.ValidationErrors
+		if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+			body.Param = validationErrs[0].Param
+			body.Errors = validationErrs
+		}
+	case errors.Is(err, ErrOverloaded):
+		status = http.StatusServiceUnavailable
+		body = errorResponse{Code: codeOverloaded, Message: err.Error()}
+	case errors.Is(err, ErrDebugForbidden):
+		status = http.StatusForbidden
+		body = errorResponse{Code: codeDebugForbidden, Message: err.Error()}
+	case errors.Is(err, ErrNotAcceptable):
+		status = http.StatusNotAcceptable
+		body = errorResponse{Code: codeNotAcceptable, Message: err.Error()}
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+		body = errorResponse{Code: codeNotFound, Message: err.Error()}
+	case errors.Is(err, ErrMethodNotAllowed):
+		status = http.StatusMethodNotAllowed
+		body = errorResponse{Code: codeMethodNotAllowed, Message: err.Error()}
+	case errors.Is(err, ErrInvalidBatch):
 		status = http.StatusBadRequest
+		body = errorResponse{Code: codeInvalidBatch, Message: err.Error()}
+	case errors.Is(err, ErrStreamingUnsupported):
+		status = http.StatusNotImplemented
+		body = errorResponse{Code: codeStreamingUnsupported, Message: err.Error()}
+	case errors.Is(err, ErrUnknownFeed):
+		status = http.StatusNotFound
+		body = errorResponse{Code: codeUnknownFeed, Message: err.Error()}
+	}
+
+	return status, body
+}
+
+func handleSuccess(w http.ResponseWriter, req *http.Request, serializer response.Serializer, resp response.Response, fields []string, private bool) {
+	// serialise upfront, so the ETag can be computed over the body
+	body := &bytes.Buffer{}
+	if err := serializer.Serialize(body, resp, fields); err != nil {
+		handleError(w, req, err)
+
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body.Bytes()))
+
+	w.Header().Set("ETag", etag)
+	cacheControl := fmt.Sprintf("max-age=%d", maxAge(resp))
+	if private {
+		cacheControl = "private, " + cacheControl
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if etagMatches(req.Header.Get("If-None-Match"), etag) {
+		status := http.StatusNotModified
+
+		w.WriteHeader(status)
+		logRequest(w, req, status, nil)
+
+		return
 	}
 
+	status := http.StatusOK
+
+	w.Header().Set("Content-Type", serializer.ContentTypes()[0])
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
 	w.WriteHeader(status)
-	logRequest(req, status, err)
+
+	if req.Method == http.MethodHead {
+		logRequest(w, req, status, nil)
+
+		return
+	}
+
+	if _, err := body.WriteTo(w); err != nil {
+		logRequest(w, req, status, err)
+
+		return
+	}
+
+	logRequest(w, req, status, nil)
+}
+
+// maxAge returns the number of seconds the response can be cached for, until the earliest item expires
+func maxAge(resp response.Response) int {
+	earliest, ok := resp.EarliestExpiry()
+	if !ok {
+		return 0
+	}
+
+	return int(math.Max(math.Floor(time.Until(earliest).Seconds()), 0))
 }
 
-func handleSuccess(w http.ResponseWriter, req *http.Request, resp response.Response) {
+// etagMatches reports whether If-None-Match header value matches the ETag, weak comparison is used
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func handleBatch(w http.ResponseWriter, req *http.Request, results []batchResult) {
 	status := http.StatusOK
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logRequest(w, req, status, err)
+
+		return
+	}
+
+	logRequest(w, req, status, nil)
+}
+
+func handleDebug(w http.ResponseWriter, req *http.Request, resp debugResponse) {
+	status := http.StatusOK
+
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logRequest(req, status, err)
+		logRequest(w, req, status, err)
 
 		return
 	}
 
-	logRequest(req, status, nil)
+	logRequest(w, req, status, nil)
 }
 
-func logRequest(req *http.Request, status int, err error) {
+func logRequest(w http.ResponseWriter, req *http.Request, status int, err error) {
+	line := fmt.Sprintf("%s %s %d", req.Method, req.URL.String(), status)
+	if variant := w.Header().Get(experimentHeaderName); variant != "" {
+		line += " variant=" + variant
+	}
+
 	if err != nil {
-		log.Printf("%s %s %d %s", req.Method, req.URL.String(), status, err.Error())
+		log.Printf("%s %s", line, err.Error())
 
 		return
 	}
 
-	log.Printf("%s %s %d", req.Method, req.URL.String(), status)
+	log.Print(line)
 }
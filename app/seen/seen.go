@@ -0,0 +1,156 @@
+// Package seen keeps track of content items already served to users.
+package seen
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxUsers   = 100 * 1000
+	defaultMaxPerUser = 1000
+
+	// sweepUsers is the number of users checked for expiry on every Add,
+	// so expired users are dropped gradually rather than in one long pause
+	sweepUsers = 100
+)
+
+// Store keeps IDs of items served to users. Implementations must be safe for concurrent use.
+type Store interface {
+	// Seen returns the subset of ids already served to the user
+	Seen(user string, ids []string) (map[string]bool, error)
+	// Add records ids as served to the user
+	Add(user string, ids []string) error
+}
+
+// MemoryStore is an in-memory Store forgetting served items after TTL
+type MemoryStore struct {
+	TTL time.Duration
+	// MaxUsers limits the number of users tracked, an arbitrary user is forgotten to make room for a new one.
+	// 0 means unlimited
+	MaxUsers int
+	// MaxPerUser limits the number of items tracked per user, items served the longest time ago are forgotten first.
+	// 0 means unlimited
+	MaxPerUser int
+
+	mu    sync.Mutex
+	users map[string]*servedItems
+}
+
+// servedItems are expiry times of items served to a user, expiry is the latest of them
+type servedItems struct {
+	items  map[string]time.Time
+	expiry time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		TTL:        ttl,
+		MaxUsers:   defaultMaxUsers,
+		MaxPerUser: defaultMaxPerUser,
+		users:      make(map[string]*servedItems),
+	}
+}
+
+// Seen returns the subset of ids served to the user within TTL
+func (s *MemoryStore) Seen(user string, ids []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	res := make(map[string]bool)
+
+	served, ok := s.users[user]
+	if !ok {
+		return res, nil
+	}
+
+	for _, id := range ids {
+		if expiry, ok := served.items[id]; ok && now.Before(expiry) {
+			res[id] = true
+		}
+	}
+
+	return res, nil
+}
+
+// Add records ids as served to the user
+func (s *MemoryStore) Add(user string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	served, ok := s.users[user]
+	if !ok {
+		s.makeRoom()
+
+		served = &servedItems{items: make(map[string]time.Time, len(ids))}
+		s.users[user] = served
+	}
+
+	// expired items of the user are dropped lazily, the number of items is limited by MaxPerUser
+	for id, expiry := range served.items {
+		if !now.Before(expiry) {
+			delete(served.items, id)
+		}
+	}
+
+	served.expiry = now.Add(s.TTL)
+	for _, id := range ids {
+		served.items[id] = served.expiry
+	}
+
+	if s.MaxPerUser > 0 && len(served.items) > s.MaxPerUser {
+		forgetOldest(served.items, len(served.items)-s.MaxPerUser)
+	}
+
+	return nil
+}
+
+// makeRoom forgets an arbitrary user if there are MaxUsers users already
+func (s *MemoryStore) makeRoom() {
+	if s.MaxUsers <= 0 || len(s.users) < s.MaxUsers {
+		return
+	}
+
+	for user := range s.users {
+		delete(s.users, user)
+
+		return
+	}
+}
+
+// forgetOldest drops n items which expire first
+func forgetOldest(served map[string]time.Time, n int) {
+	ids := make([]string, 0, len(served))
+	for id := range served {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return served[ids[i]].Before(served[ids[j]])
+	})
+
+	for _, id := range ids[:n] {
+		delete(served, id)
+	}
+}
+
+// sweep drops up to sweepUsers users whose items have all expired. Map iteration order is random,
+// so the whole map is swept gradually while items are added.
+func (s *MemoryStore) sweep(now time.Time) {
+	checked := 0
+	for user, served := range s.users {
+		if checked == sweepUsers {
+			return
+		}
+		checked++
+
+		if !now.Before(served.expiry) {
+			delete(s.users, user)
+		}
+	}
+}
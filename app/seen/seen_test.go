@@ -0,0 +1,107 @@
+package seen
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Seen(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond * 50)
+
+	if err := store.Add("user", []string{"1", "2"}); err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+
+	seen, err := store.Seen("user", []string{"1", "2", "3"})
+	if err != nil {
+		t.Fatalf("unexpected error '%v'", err)
+	}
+	if len(seen) != 2 || !seen["1"] || !seen["2"] {
+		t.Errorf("Got seen items %v, want 1 and 2", seen)
+	}
+
+	if seen, _ = store.Seen("other", []string{"1"}); len(seen) != 0 {
+		t.Errorf("Got seen items %v for another user, want none", seen)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	if seen, _ = store.Seen("user", []string{"1", "2"}); len(seen) != 0 {
+		t.Errorf("Got seen items %v after TTL, want none", seen)
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond * 50)
+
+	_ = store.Add("user", []string{"1", "2"})
+	time.Sleep(time.Millisecond * 60)
+
+	// adding items sweeps expired users
+	_ = store.Add("other", []string{"3"})
+
+	if _, ok := store.users["user"]; ok {
+		t.Errorf("Expired user is still tracked")
+	}
+	if len(store.users["other"].items) != 1 {
+		t.Errorf("Got %d items tracked for a new user, want 1", len(store.users["other"].items))
+	}
+
+	// expired items of a user are dropped when the user gets new ones
+	_ = store.Add("other", []string{"4"})
+	time.Sleep(time.Millisecond * 60)
+	_ = store.Add("other", []string{"5"})
+
+	if len(store.users["other"].items) != 1 {
+		t.Errorf("Got %d items tracked after the others expired, want 1", len(store.users["other"].items))
+	}
+}
+
+func TestMemoryStore_SweepGradually(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond * 50)
+	for i := 0; i < sweepUsers*3; i++ {
+		_ = store.Add(strconv.Itoa(i), []string{"1"})
+	}
+	time.Sleep(time.Millisecond * 60)
+
+	// every Add checks a limited number of users
+	_ = store.Add("new", []string{"1"})
+	if remaining := len(store.users); remaining < sweepUsers*2 {
+		t.Fatalf("Got %d users left after one Add, want at most %d swept", remaining, sweepUsers)
+	}
+
+	for i := 0; i < 1000 && len(store.users) > 1; i++ {
+		_ = store.Add("new", []string{"1"})
+	}
+	if len(store.users) != 1 {
+		t.Errorf("Got %d users tracked, want expired users swept", len(store.users))
+	}
+}
+
+func TestMemoryStore_Limits(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	store.MaxUsers = 2
+	store.MaxPerUser = 3
+
+	for i := 0; i < 5; i++ {
+		_ = store.Add("user", []string{strconv.Itoa(i)})
+		time.Sleep(time.Millisecond)
+	}
+
+	seen, _ := store.Seen("user", []string{"0", "1", "2", "3", "4"})
+	if len(seen) != 3 || !seen["2"] || !seen["3"] || !seen["4"] {
+		t.Errorf("Got seen items %v, want the last 3 items", seen)
+	}
+
+	for _, user := range []string{"a", "b", "c"} {
+		_ = store.Add(user, []string{"1"})
+	}
+
+	if len(store.users) != 2 {
+		t.Errorf("Got %d users tracked, want 2", len(store.users))
+	}
+	if seen, _ := store.Seen("c", []string{"1"}); !seen["1"] {
+		t.Errorf("The most recent user isn't tracked")
+	}
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/request"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/seen"
+)
+
+const (
+	streamPath    = "/stream"
+	feedParamName = "feed"
+
+	defaultStreamInterval    = time.Second * 30
+	defaultStreamHeartbeat   = time.Second * 15
+	defaultStreamMaxBuffered = 100
+
+	// streamSeenTTL is how long items sent over a connection aren't sent again, if App.Seen isn't set
+	streamSeenTTL = time.Hour
+)
+
+var (
+	ErrStreamingUnsupported = errors.New("streaming is not supported")
+	errSlowClient           = errors.New("client doesn't keep up with the stream")
+)
+
+// StreamConfig configures the stream of new content items served as server-sent events
+type StreamConfig struct {
+	// Interval is how often new items are loaded from providers, 30 seconds by default
+	Interval time.Duration
+	// Heartbeat is how often a comment is sent to keep idle connections open, 15 seconds by default
+	Heartbeat time.Duration
+	// MaxBuffered is the number of items waiting to be sent to a client,
+	// the connection is closed once a slow client falls further behind. It limits count as well. 100 by default
+	MaxBuffered int
+}
+
+// streamEvent is a content item sent to the client, ID is the item position in the feed
+type streamEvent struct {
+	id   uint64
+	item provider.ContentItem
+}
+
+// serveStream subscribes the client to a feed, sending new items in the content mix order as server-sent events.
+// Every poll loads count items, the position of the last item sent is the event ID, so a reconnecting client
+// continues after it with Last-Event-ID header.
+func (a App) serveStream(w http.ResponseWriter, httpReq *http.Request) {
+	if httpReq.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		handleError(w, httpReq, ErrMethodNotAllowed)
+
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(w, httpReq, ErrStreamingUnsupported)
+
+		return
+	}
+
+	req := &request.Request{}
+	if err := req.Parse(httpReq); err != nil {
+		handleError(w, httpReq, err)
+
+		return
+	}
+	if err := req.ParseLastEventID(httpReq); err != nil {
+		handleError(w, httpReq, err)
+
+		return
+	}
+
+	settings := a.streamConfig()
+
+	// every poll sends up to count items at once, which have to fit into the buffer
+	if err := req.LimitCount(settings.MaxBuffered); err != nil {
+		handleError(w, httpReq, err)
+
+		return
+	}
+
+	mix, variant, err := a.feed(*req, httpReq.URL.Query().Get(feedParamName))
+	if err != nil {
+		handleError(w, httpReq, err)
+
+		return
+	}
+
+	if a.shedLoad(w, httpReq) {
+		return
+	}
+
+	if variant != "" {
+		w.Header().Set(experimentHeaderName, variant)
+		experimentVariants.Add(variant, 1)
+	}
+
+	position := req.Offset
+	if req.Resume {
+		position = req.LastEventID + 1
+	}
+
+	// items sent over the connection are suppressed when polling again
+	if a.Seen == nil {
+		a.Seen = seen.NewMemoryStore(streamSeenTTL)
+	}
+
+	fields := responseFields(*req)
+
+	ctx, cancel := context.WithCancel(httpReq.Context())
+	defer cancel()
+
+	events := make(chan streamEvent, settings.MaxBuffered)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- a.pollItems(ctx, *req, mix, position, settings.Interval, events)
+	}()
+
+	status := http.StatusOK
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(settings.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			err = writeEvent(w, event, fields)
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n")
+		case err = <-done:
+		case <-ctx.Done():
+			logRequest(w, httpReq, status, nil)
+
+			return
+		}
+
+		if err != nil {
+			logRequest(w, httpReq, status, err)
+
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// pollItems loads new items every interval and sends them to events, starting from the given position.
+// It gives up with errSlowClient once events are full.
+func (a App) pollItems(
+	ctx context.Context,
+	req request.Request,
+	mix config.ContentMix,
+	position uint64,
+	interval time.Duration,
+	events chan<- streamEvent,
+) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		req.Offset = position
+
+		resultsPerProvider := a.loadResults(req, mix)
+		seenItems := a.seenItems(req, resultsPerProvider)
+		resp, _ := a.prepareResponse(req, mix, resultsPerProvider, seenItems)
+		a.recordSeenItems(req, resp)
+
+		for i := range resp {
+			select {
+			case events <- streamEvent{id: position, item: resp[i]}:
+				position++
+			default:
+				return errSlowClient
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event streamEvent, fields []string) error {
+	var item interface{} = event.item
+	if len(fields) > 0 {
+		item = response.Response{event.item}.Select(fields)[0]
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: item\ndata: %s\n\n", event.id, data)
+
+	return err
+}
+
+// streamConfig returns the stream configuration with defaults set
+func (a App) streamConfig() StreamConfig {
+	settings := a.Stream
+	if settings.Interval <= 0 {
+		settings.Interval = defaultStreamInterval
+	}
+	if settings.Heartbeat <= 0 {
+		settings.Heartbeat = defaultStreamHeartbeat
+	}
+	if settings.MaxBuffered <= 0 {
+		settings.MaxBuffered = defaultStreamMaxBuffered
+	}
+
+	return settings
+}
@@ -2,28 +2,73 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/dmitriivoitovich/test-assignment-sliide/app"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/admin"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/config"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/moderation"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/normalize"
 	"github.com/dmitriivoitovich/test-assignment-sliide/app/provider"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/response"
+	"github.com/dmitriivoitovich/test-assignment-sliide/app/seen"
+)
+
+const (
+	blocklistReloadInterval = time.Second * 10
 )
 
 var (
 	addr = flag.String("addr", "127.0.0.1:8080", "the TCP address for the server to listen on, in the form 'host:port'")
 
+	callsPerMinute = flag.Int("provider-calls-per-minute", 0, "max number of calls to each provider per minute, 0 means unlimited")
+	callsPerDay    = flag.Int("provider-calls-per-day", 0, "max number of calls to each provider per day, 0 means unlimited")
+
+	maxCalls            = flag.Int("max-provider-calls", 1000, "max number of provider calls in flight")
+	maxCallsPerProvider = flag.Int("max-provider-calls-per-provider", 200, "max number of calls in flight to a single provider")
+	maxQueuedCalls      = flag.Int("max-queued-provider-calls", 500, "number of provider calls waiting for a slot after which requests are rejected, or calls to a single provider fail")
+
+	debugToken  = flag.String("debug-token", "", "token to pass in X-Debug-Token header to get debug output with debug=1, debug mode is disabled if empty")
+	adminTokens = flag.String("admin-tokens", "", "comma separated list of 'user:token' pairs allowed to use the admin API, admin API is disabled if empty")
+	chaos       = flag.Bool("chaos", false, "wrap provider clients with fault injection configurable via the admin API")
+
+	streamInterval  = flag.Duration("stream-interval", 30*time.Second, "how often new items are loaded for clients of the event stream")
+	streamHeartbeat = flag.Duration("stream-heartbeat", 15*time.Second, "how often a heartbeat is sent to idle clients of the event stream")
+	streamBuffer    = flag.Int("stream-buffer", 100, "max number of items waiting to be sent to a client of the event stream before it's disconnected")
+
+	compressMinSize = flag.Int("compress-min-size", 1024, "min response size in bytes to compress with gzip or deflate")
+
+	seenTTL = flag.Duration("seen-ttl", 24*time.Hour, "how long items already served to a user are suppressed for, 0 disables suppression")
+
+	feedTitle       = flag.String("feed-title", "News", "title of the feed served as RSS or Atom")
+	feedLink        = flag.String("feed-link", "http://127.0.0.1:8080/", "link to the feed served as RSS or Atom")
+	feedDescription = flag.String("feed-description", "Latest news from all providers", "description of the feed served as RSS or Atom")
+
+	schedule   = flag.String("schedule", "", "JSON file with content mixes used within time windows, and the timezone windows are defined in")
+	experiment = flag.String("experiment", "", "JSON file with an experiment splitting users between content mix variants")
+
+	feeds = flag.String("feeds", "", "JSON file with content mixes by feed name, served by the batch endpoint besides the default feed")
+
+	maxSummaryLength = flag.Int("max-summary-length", 300, "max number of characters in item summaries, 0 means summaries aren't truncated")
+	blocklist        = flag.String("blocklist", "", "JSON file with domains, IDs and keywords of content to block, reloaded when changed")
+
+	record = flag.String("record", "", "append provider requests and responses to this JSON lines file")
+	replay = flag.String("replay", "", "serve provider responses recorded with -record from this file instead of calling providers")
+
+	// as an example we've added 3 providers
+	providers = []provider.Provider{provider.Provider1, provider.Provider2, provider.Provider3}
+
 	// app gets initialised with configuration.
-	// as an example we've added 3 providers and a default configuration
+	// as an example we use the default configuration
 	handler = app.App{
-		ContentClients: map[provider.Provider]provider.Client{
-			provider.Provider1: &provider.SampleContentProvider{Source: provider.Provider1},
-			provider.Provider2: &provider.SampleContentProvider{Source: provider.Provider2},
-			provider.Provider3: &provider.SampleContentProvider{Source: provider.Provider3},
-		},
 		Config: config.DefaultContentMix,
 	}
 )
@@ -32,12 +77,123 @@ func main() {
 	flag.Parse()
 	log.Printf("initalising server on %s", *addr)
 
+	idleConnsClosed := make(chan struct{})
+
+	var recorder *provider.Recorder
+	if *record != "" {
+		file, err := os.OpenFile(*record, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("couldn't open recording file: %v", err)
+		}
+		defer file.Close()
+
+		recorder = provider.NewRecorder(file)
+	}
+
+	chaosClients := make(map[provider.Provider]*provider.ChaosClient)
+	handler.ContentClients = make(map[provider.Provider]provider.Client, len(providers))
+	handler.Budgets = make(map[provider.Provider]*provider.Budget, len(providers))
+	for _, providerType := range providers {
+		var client provider.Client = &provider.SampleContentProvider{Source: providerType}
+		if *replay != "" {
+			replayClient, err := provider.LoadReplayClient(*replay, providerType)
+			if err != nil {
+				log.Fatalf("couldn't load recorded provider traffic: %v", err)
+			}
+
+			replayClient.Delay = true
+			client = replayClient
+		}
+		if recorder != nil {
+			client = recorder.Wrap(providerType, client)
+		}
+		if *chaos {
+			chaosClients[providerType] = provider.NewChaosClient(client)
+			client = chaosClients[providerType]
+		}
+
+		// budget is checked inside the coalescing client, so a shared call uses the budget once
+		handler.Budgets[providerType] = provider.NewBudget(*callsPerMinute, *callsPerDay)
+		client = provider.NewBudgetedClient(client, handler.Budgets[providerType])
+
+		handler.ContentClients[providerType] = provider.NewCoalescingClient(client, provider.SegmentByNetwork)
+	}
+
+	expvar.Publish("provider_budget", expvar.Func(handler.BudgetStats))
+
+	handler.Limiter = app.NewLimiter(*maxCalls, *maxCallsPerProvider, *maxQueuedCalls)
+	handler.DebugToken = *debugToken
+	handler.Stream = app.StreamConfig{Interval: *streamInterval, Heartbeat: *streamHeartbeat, MaxBuffered: *streamBuffer}
+	handler.Mixes = config.NewStore(config.DefaultContentMix, providers)
+	if *schedule != "" {
+		var err error
+
+		handler.Schedule, err = config.LoadSchedule(*schedule, providers)
+		if err != nil {
+			log.Fatalf("couldn't load schedule: %v", err)
+		}
+	}
+	if *experiment != "" {
+		var err error
+
+		handler.Experiment, err = config.LoadExperiment(*experiment, providers)
+		if err != nil {
+			log.Fatalf("couldn't load experiment: %v", err)
+		}
+	}
+	if *feeds != "" {
+		var err error
+
+		handler.Feeds, err = config.LoadFeeds(*feeds, providers)
+		if err != nil {
+			log.Fatalf("couldn't load feeds: %v", err)
+		}
+	}
+	channel := response.Channel{Title: *feedTitle, Link: *feedLink, Description: *feedDescription, Language: "en"}
+	handler.Serializers = append(response.Serializers{}, response.DefaultSerializers...)
+	handler.Serializers = append(handler.Serializers, response.RSS{Channel: channel}, response.Atom{Channel: channel})
+
+	handler.Normalizer = &normalize.Normalizer{MaxSummaryLength: *maxSummaryLength}
+	if *blocklist != "" {
+		moderator, err := moderation.LoadModerator(*blocklist)
+		if err != nil {
+			log.Fatalf("couldn't load blocklist: %v", err)
+		}
+
+		go moderator.Watch(blocklistReloadInterval, idleConnsClosed)
+		handler.Moderator = moderator
+	} else {
+		handler.Moderator = moderation.NewModerator(moderation.Blocklist{})
+	}
+	if *seenTTL > 0 {
+		handler.Seen = seen.NewMemoryStore(*seenTTL)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	if tokens := parseAdminTokens(*adminTokens); len(tokens) > 0 {
+		mux.Handle("/admin/chaos", admin.Authenticate(tokens, admin.ChaosHandler{Clients: chaosClients}))
+
+		mixHandler := admin.Authenticate(tokens, admin.MixHandler{Prefix: "/admin/mix", Store: handler.Mixes})
+		mux.Handle("/admin/mix", mixHandler)
+		mux.Handle("/admin/mix/", mixHandler)
+
+		mux.Handle("/admin/schedule", admin.Authenticate(tokens, admin.ScheduleHandler{Schedule: handler.Schedule, Store: handler.Mixes}))
+		mux.Handle("/admin/blocklist", admin.Authenticate(tokens, admin.ModerationHandler{Moderator: handler.Moderator}))
+	}
+
+	mux.Handle("/", app.Compress(handler, *compressMinSize))
+
+	// requests are cancelled on shutdown, so event streams don't keep it waiting
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
 	srv := http.Server{
-		Addr:    *addr,
-		Handler: handler,
+		Addr:        *addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelRequests)
 
-	idleConnsClosed := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt)
@@ -58,3 +214,19 @@ func main() {
 
 	<-idleConnsClosed
 }
+
+// parseAdminTokens parses a list of 'user:token' pairs into a map of tokens to users
+func parseAdminTokens(value string) map[string]string {
+	tokens := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+
+		tokens[parts[1]] = parts[0]
+	}
+
+	return tokens
+}